@@ -0,0 +1,65 @@
+package main
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"strings"
+)
+
+func NewAdminAuthentication(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			prefix := "Bearer "
+			authorizationHeader := r.Header.Get("Authorization")
+			if !strings.HasPrefix(authorizationHeader, prefix) {
+				writeJsonError(w, http.StatusUnauthorized, "admin token required")
+				return
+			}
+
+			bearer := authorizationHeader[len(prefix):]
+			if subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) != 1 {
+				writeJsonError(w, http.StatusForbidden, "invalid admin token")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func NewReloadHandler(catalog *CatalogHolder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("reload of catalog requested")
+
+		diff, err := catalog.Reload()
+		if err != nil {
+			log.Println("failed to reload catalog, keep serving the last good one:", err)
+			writeJsonError(w, http.StatusInternalServerError, "failed to reload catalog: "+err.Error())
+			return
+		}
+
+		log.Println("catalog reloaded:", diff)
+		writeJson(w, http.StatusOK, diff)
+	}
+}
+
+type Diagnostics struct {
+	Problems        Problems `json:"problems"`
+	LastReloadError string   `json:"lastReloadError,omitempty"`
+}
+
+func NewDiagnosticsHandler(catalog *CatalogHolder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		diagnostics := Diagnostics{
+			Problems: catalog.Get().Problems,
+		}
+		if diagnostics.Problems == nil {
+			diagnostics.Problems = Problems{}
+		}
+		if err := catalog.LastError(); err != nil {
+			diagnostics.LastReloadError = err.Error()
+		}
+		writeJson(w, http.StatusOK, diagnostics)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAdminAuthenticationWithoutToken(t *testing.T) {
+	rr := serveAdminRequest(t, "", NewOkHandler())
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+}
+
+func TestAdminAuthenticationWithWrongToken(t *testing.T) {
+	rr := serveAdminRequest(t, "Bearer wrong", NewOkHandler())
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+}
+
+func TestAdminAuthenticationWithToken(t *testing.T) {
+	rr := serveAdminRequest(t, "Bearer secret", NewOkHandler())
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestReloadHandlerReturnsDiff(t *testing.T) {
+	catalogs := []*Catalog{NewCatalog(testData[:1], nil), NewCatalog(testData, nil)}
+	calls := 0
+	holder, err := NewCatalogHolder(func() (*Catalog, error) {
+		catalog := catalogs[calls]
+		calls++
+		return catalog, nil
+	})
+	assert.NoError(t, err)
+
+	rr := serveAdminRequest(t, "Bearer secret", NewReloadHandler(holder))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var diff CatalogDiff
+	err = json.Unmarshal(rr.Body.Bytes(), &diff)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ad-plugin"}, diff.PluginsAdded)
+	assert.Equal(t, []string{"1.0"}, diff.Releases["ad-plugin"].Added)
+	assert.Len(t, holder.Get().Plugins, 2)
+}
+
+func TestReloadHandlerReturnsErrorOnFailedReload(t *testing.T) {
+	fail := false
+	holder, err := NewCatalogHolder(func() (*Catalog, error) {
+		if fail {
+			return nil, errors.New("broken release")
+		}
+		return NewCatalog(testData, nil), nil
+	})
+	assert.NoError(t, err)
+	fail = true
+
+	rr := serveAdminRequest(t, "Bearer secret", NewReloadHandler(holder))
+
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	assert.Contains(t, rr.Body.String(), "broken release")
+	assert.Len(t, holder.Get().Plugins, 2)
+}
+
+func serveAdminRequest(t *testing.T, authorization string, handler http.Handler) *httptest.ResponseRecorder {
+	req, err := http.NewRequest("POST", "/api/v1/admin/reload", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	rr := httptest.NewRecorder()
+	NewAdminAuthentication("secret")(handler).ServeHTTP(rr, req)
+	return rr
+}
+
+func TestDiagnosticsHandlerReturnsProblems(t *testing.T) {
+	catalog := NewCatalog(testData, nil)
+	catalog.Problems = Problems{{File: "next.yml", Line: 2, Field: "tag", Message: "next is not a valid version"}}
+
+	rr := serveAdminRequest(t, "Bearer secret", NewDiagnosticsHandler(staticCatalog(catalog)))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"problems":[{"file":"next.yml","line":2,"field":"tag","message":"next is not a valid version"}]}`, rr.Body.String())
+}
+
+func TestDiagnosticsHandlerReturnsLastReloadError(t *testing.T) {
+	fail := false
+	holder, err := NewCatalogHolder(func() (*Catalog, error) {
+		if fail {
+			return nil, errors.New("broken release")
+		}
+		return NewCatalog(testData, nil), nil
+	})
+	assert.NoError(t, err)
+	fail = true
+	_, _ = holder.Reload()
+
+	rr := serveAdminRequest(t, "Bearer secret", NewDiagnosticsHandler(holder))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"problems":[],"lastReloadError":"broken release"}`, rr.Body.String())
+}
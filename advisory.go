@@ -0,0 +1,46 @@
+package main
+
+const (
+	SeverityLow      = "low"
+	SeverityMedium   = "medium"
+	SeverityHigh     = "high"
+	SeverityCritical = "critical"
+)
+
+var severities = map[string]bool{
+	SeverityLow:      true,
+	SeverityMedium:   true,
+	SeverityHigh:     true,
+	SeverityCritical: true,
+}
+
+// Advisory describes a security problem of the release in which it is declared.
+type Advisory struct {
+	Id          string `yaml:"id,omitempty" json:"id,omitempty"`
+	Severity    string `yaml:"severity,omitempty" json:"severity"`
+	Description string `yaml:"description,omitempty" json:"description"`
+	FixedIn     string `yaml:"fixedIn,omitempty" json:"fixedIn,omitempty"`
+}
+
+type AdvisoryResult struct {
+	Advisory
+	AffectedVersions []string `json:"affectedVersions"`
+}
+
+// collectAdvisories merges the advisories of all releases of the plugin, so that instances are able to warn about
+// installed versions, which are no longer offered. Advisories with the same id are reported only once.
+func collectAdvisories(plugin Plugin) []AdvisoryResult {
+	var results []AdvisoryResult
+	indices := make(map[string]int)
+	for _, release := range plugin.Releases {
+		for _, advisory := range release.Advisories {
+			if index, ok := indices[advisory.Id]; ok && advisory.Id != "" {
+				results[index].AffectedVersions = append(results[index].AffectedVersions, release.Version)
+				continue
+			}
+			indices[advisory.Id] = len(results)
+			results = append(results, AdvisoryResult{Advisory: advisory, AffectedVersions: []string{release.Version}})
+		}
+	}
+	return results
+}
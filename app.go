@@ -15,6 +15,13 @@ import (
 var assets embed.FS
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "validate" {
+		os.Exit(runValidate(os.Args[2:], os.Stdout))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "mirror" {
+		os.Exit(runMirror(os.Args[2:], os.Stdout))
+	}
+
 	configuration := readConfiguration()
 	r := configureRouter(configuration)
 
@@ -33,14 +40,35 @@ func getListenerAddress(port int) string {
 }
 
 func configureRouter(configuration Configuration) *mux.Router {
-	plugins, err := scanDirectory(configuration.DescriptorDirectory)
+	catalog, err := NewCatalogHolder(NewDirectoryCatalogLoader(configuration))
+	if err != nil {
+		log.Fatalln("could not load catalog", err)
+	}
+
+	if configuration.ReloadInterval > 0 {
+		catalog.Watch(configuration.ReloadInterval, configuration.DescriptorDirectory, configuration.PluginSetsDirectory)
+	} else {
+		log.Println("plugin center api starts without automatic catalog reload")
+	}
+
+	var cache *ArtifactCache
+	if configuration.CacheDirectory != "" {
+		cache, err = NewArtifactCache(configuration.CacheDirectory, configuration.CacheSize)
+		if err != nil {
+			log.Fatalln("could not create artifact cache", err)
+		}
+	} else {
+		log.Println("plugin center api starts without artifact cache")
+	}
+
+	artifactStore, err := NewArtifactStore(configuration)
 	if err != nil {
-		log.Fatalln("could not parse plugins", err)
+		log.Fatalln("could not create artifact store", err)
 	}
 
-	pluginSets, err := scanPluginSetsDirectory(configuration.PluginSetsDirectory)
+	downloadStrategies, err := NewDownloadStrategies(configuration)
 	if err != nil {
-		log.Fatalln("could not parse plugin sets", err)
+		log.Fatalln("invalid download strategies", err)
 	}
 
 	static, err := fs.Sub(assets, "html")
@@ -71,8 +99,24 @@ func configureRouter(configuration Configuration) *mux.Router {
 	}
 
 	// api
-	r.Handle("/api/v1/plugins/{version}", authentication(NewPluginHandler(plugins, pluginSets)))
-	r.Handle("/api/v1/download/{plugin}/{version}", authentication(NewDownloadHandler(plugins)))
+	r.Handle("/api/v1/plugins/{version}", authentication(NewPluginHandler(catalog)))
+	r.Handle("/api/v1/plugins/{version}/{plugin}/releases", authentication(NewReleasesHandler(catalog)))
+	r.Handle("/api/v1/plugin/{name}", authentication(NewPluginDetailHandler(catalog)))
+	r.Handle("/api/v1/download/{plugin}/{version}", authentication(NewDownloadHandler(catalog, artifactStore, cache, downloadStrategies)))
+	r.Handle("/api/v1/resolve/{version}", authentication(NewResolveHandler(catalog)))
+	r.Handle("/api/v1/plugin-sets/{version}", authentication(NewPluginSetHandler(catalog)))
+	r.Handle("/api/v1/plugin-sets/{id}/images/{name}", NewPluginSetImageHandler(catalog))
+
+	// admin
+	if configuration.AdminToken != "" {
+		adminAuthentication := NewAdminAuthentication(configuration.AdminToken)
+
+		r.Handle("/api/v1/admin/reload", adminAuthentication(NewReloadHandler(catalog))).Methods("POST")
+		r.Handle("/api/v1/admin/diagnostics", adminAuthentication(NewDiagnosticsHandler(catalog))).Methods("GET")
+		r.Handle("/api/v1/admin/plugins/{name}/releases", adminAuthentication(NewPublishHandler(catalog, configuration.DescriptorDirectory))).Methods("POST")
+	} else {
+		log.Println("plugin center api starts without admin api, because no admin token is configured")
+	}
 
 	// static assets
 	r.PathPrefix("/static").Handler(http.FileServer(http.FS(static)))
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/pkg/errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	artifactConnectTimeout = 10 * time.Second
+	artifactReadTimeout    = 30 * time.Second
+)
+
+// errArtifactUnavailable is returned, if the store could be reached but does not deliver the artifact
+var errArtifactUnavailable = errors.New("artifact unavailable")
+
+// errArtifactNotAllowed is returned, if the location of the artifact is outside of the artifact directory
+var errArtifactNotAllowed = errors.New("artifact location not allowed")
+
+// Artifact is the content of a plugin release
+type Artifact struct {
+	Body io.ReadCloser
+	// Size of the artifact in bytes or -1, if the size is unknown
+	Size        int64
+	ContentType string
+}
+
+// ArtifactStore opens the artifact, which is referenced by the url of a release.
+type ArtifactStore interface {
+	Open(location string) (*Artifact, error)
+}
+
+// NewArtifactStore creates a store which selects the backend by the scheme of the location. Urls with the scheme
+// http or https are downloaded, s3 urls are read from the configured object storage and file urls or paths
+// are read from the artifact directory, which defaults to the descriptor directory.
+func NewArtifactStore(configuration Configuration) (ArtifactStore, error) {
+	artifactDirectory := configuration.ArtifactDirectory
+	if artifactDirectory == "" {
+		artifactDirectory = configuration.DescriptorDirectory
+	}
+	fileStore, err := newFileArtifactStore(artifactDirectory)
+	if err != nil {
+		return nil, err
+	}
+	stores := schemeArtifactStore{
+		http: &httpArtifactStore{get: newArtifactHttpClient(artifactReadTimeout).Get},
+		file: fileStore,
+	}
+	if configuration.S3.IsEnabled() {
+		s3Store, err := newS3ArtifactStore(configuration.S3)
+		if err != nil {
+			return nil, err
+		}
+		stores.s3 = s3Store
+	}
+	return stores, nil
+}
+
+type schemeArtifactStore struct {
+	http ArtifactStore
+	file ArtifactStore
+	s3   ArtifactStore
+}
+
+func (s schemeArtifactStore) Open(location string) (*Artifact, error) {
+	switch scheme(location) {
+	case "http", "https":
+		return s.http.Open(location)
+	case "file", "":
+		return s.file.Open(location)
+	case "s3":
+		if s.s3 == nil {
+			return nil, errors.Errorf("could not open %s, because no s3 storage is configured", location)
+		}
+		return s.s3.Open(location)
+	default:
+		return nil, errors.Errorf("could not open %s, because the scheme is not supported", location)
+	}
+}
+
+func scheme(location string) string {
+	index := strings.Index(location, "://")
+	if index < 0 {
+		return ""
+	}
+	return strings.ToLower(location[:index])
+}
+
+func isHttpUrl(location string) bool {
+	s := scheme(location)
+	return s == "http" || s == "https"
+}
+
+// newArtifactHttpClient creates a client for artifact downloads. The client has no timeout for the whole request,
+// because large artifacts may take a while, but it aborts connections which do not deliver data for the read timeout.
+func newArtifactHttpClient(readTimeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: artifactConnectTimeout, KeepAlive: 30 * time.Second}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, network string, address string) (net.Conn, error) {
+		conn, err := dialer.DialContext(ctx, network, address)
+		if err != nil {
+			return nil, err
+		}
+		return &idleTimeoutConn{Conn: conn, timeout: readTimeout}, nil
+	}
+	transport.TLSHandshakeTimeout = artifactConnectTimeout
+	transport.ResponseHeaderTimeout = readTimeout
+	return &http.Client{Transport: transport}
+}
+
+// idleTimeoutConn fails reads which receive no data within the timeout, so that stalled downloads are aborted.
+type idleTimeoutConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c *idleTimeoutConn) Read(data []byte) (int, error) {
+	if err := c.Conn.SetReadDeadline(time.Now().Add(c.timeout)); err != nil {
+		return 0, err
+	}
+	return c.Conn.Read(data)
+}
+
+type httpArtifactStore struct {
+	get func(url string) (resp *http.Response, err error)
+}
+
+func (s *httpArtifactStore) Open(location string) (*Artifact, error) {
+	resp, err := s.get(location)
+	if err != nil {
+		return nil, err
+	}
+	return artifactFromResponse(resp, location)
+}
+
+func artifactFromResponse(resp *http.Response, location string) (*Artifact, error) {
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		_ = resp.Body.Close()
+		return nil, errors.Wrapf(errArtifactUnavailable, "got status %d from %s", resp.StatusCode, location)
+	}
+	return &Artifact{
+		Body:        resp.Body,
+		Size:        resp.ContentLength,
+		ContentType: resp.Header.Get("Content-Type"),
+	}, nil
+}
+
+// fileArtifactStore reads artifacts from the local filesystem, but only from inside of the root directory.
+// Relative paths are resolved against the root directory.
+type fileArtifactStore struct {
+	root string
+}
+
+func newFileArtifactStore(root string) (fileArtifactStore, error) {
+	if root == "" {
+		return fileArtifactStore{}, nil
+	}
+	absoluteRoot, err := filepath.Abs(root)
+	if err != nil {
+		return fileArtifactStore{}, errors.Wrapf(err, "could not resolve artifact directory %s", root)
+	}
+	return fileArtifactStore{root: absoluteRoot}, nil
+}
+
+func (s fileArtifactStore) Open(location string) (*Artifact, error) {
+	path := location
+	if scheme(location) == "file" {
+		fileUrl, err := url.Parse(location)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not parse file url %s", location)
+		}
+		path = fileUrl.Path
+	}
+
+	path, err := s.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, errors.Wrapf(errArtifactUnavailable, "file %s does not exist", path)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not open file %s", path)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, errors.Wrapf(err, "could not stat file %s", path)
+	}
+	if info.IsDir() {
+		_ = file.Close()
+		return nil, errors.Wrapf(errArtifactUnavailable, "%s is a directory", path)
+	}
+	return &Artifact{Body: file, Size: info.Size()}, nil
+}
+
+// resolve returns the absolute path of the artifact or errArtifactNotAllowed, if the path is not inside of the root.
+func (s fileArtifactStore) resolve(path string) (string, error) {
+	if s.root == "" {
+		return "", errors.Wrapf(errArtifactNotAllowed, "no artifact directory is configured to read %s", path)
+	}
+	if filepath.IsAbs(path) {
+		relative, err := filepath.Rel(s.root, path)
+		if err != nil {
+			return "", errors.Wrapf(errArtifactNotAllowed, "%s is not inside of %s", path, s.root)
+		}
+		path = relative
+	}
+	resolved, err := joinWithin(s.root, path)
+	if err != nil {
+		return "", errors.Wrap(errArtifactNotAllowed, err.Error())
+	}
+	return resolved, nil
+}
+
+// writeArtifact writes the content of the reader to the path and returns the sha256 checksum of the content.
+// If an expected checksum is passed and the content does not match, nothing is written and errChecksumMismatch
+// is returned.
+func writeArtifact(path string, r io.Reader, expectedChecksum string) (string, error) {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return "", errors.Wrapf(err, "could not create directory for %s", path)
+	}
+	temp, err := ioutil.TempFile(filepath.Dir(path), ".artifact-")
+	if err != nil {
+		return "", errors.Wrapf(err, "could not create temporary file for %s", path)
+	}
+	defer func() {
+		_ = os.Remove(temp.Name())
+	}()
+
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(temp, hash), r)
+	closeErr := temp.Close()
+	if err != nil {
+		return "", errors.Wrapf(err, "could not read artifact for %s", path)
+	}
+	if closeErr != nil {
+		return "", errors.Wrapf(closeErr, "could not write %s", path)
+	}
+
+	checksum := hex.EncodeToString(hash.Sum(nil))
+	if expectedChecksum != "" && !strings.EqualFold(checksum, expectedChecksum) {
+		return checksum, errChecksumMismatch
+	}
+	return checksum, errors.Wrapf(os.Rename(temp.Name(), path), "could not move artifact to %s", path)
+}
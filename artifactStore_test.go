@@ -0,0 +1,136 @@
+package main
+
+import (
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readArtifact(t *testing.T, store ArtifactStore, location string) string {
+	artifact, err := store.Open(location)
+	assert.NoError(t, err)
+	defer func() {
+		_ = artifact.Body.Close()
+	}()
+	content, err := ioutil.ReadAll(artifact.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(content)), artifact.Size)
+	return string(content)
+}
+
+func TestFileArtifactStoreReadsPathsAndFileUrls(t *testing.T) {
+	directory := t.TempDir()
+	path := filepath.Join(directory, "scm-mail-plugin.smp")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("content"), 0644))
+
+	store, err := NewArtifactStore(Configuration{ArtifactDirectory: directory})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "content", readArtifact(t, store, path))
+	assert.Equal(t, "content", readArtifact(t, store, "file://"+path))
+	assert.Equal(t, "content", readArtifact(t, store, "scm-mail-plugin.smp"))
+}
+
+func TestFileArtifactStoreReadsFromDescriptorDirectoryByDefault(t *testing.T) {
+	directory := t.TempDir()
+	path := filepath.Join(directory, "scm-mail-plugin.smp")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("content"), 0644))
+
+	store, err := NewArtifactStore(Configuration{DescriptorDirectory: directory})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "content", readArtifact(t, store, path))
+}
+
+func TestFileArtifactStoreRejectsPathsOutsideOfArtifactDirectory(t *testing.T) {
+	parent := t.TempDir()
+	directory := filepath.Join(parent, "artifacts")
+	assert.NoError(t, os.Mkdir(directory, 0755))
+	outside := filepath.Join(parent, "secret.txt")
+	assert.NoError(t, ioutil.WriteFile(outside, []byte("secret"), 0644))
+
+	store, err := NewArtifactStore(Configuration{ArtifactDirectory: directory})
+	assert.NoError(t, err)
+
+	for _, location := range []string{outside, "file://" + outside, "../secret.txt", "/etc/passwd"} {
+		_, err := store.Open(location)
+		assert.Equal(t, errArtifactNotAllowed, errors.Cause(err), location)
+	}
+}
+
+func TestFileArtifactStoreWithoutDirectoryRejectsAllPaths(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scm-mail-plugin.smp")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("content"), 0644))
+
+	store, err := NewArtifactStore(Configuration{})
+	assert.NoError(t, err)
+
+	_, err = store.Open(path)
+	assert.Equal(t, errArtifactNotAllowed, errors.Cause(err))
+}
+
+func TestFileArtifactStoreReportsMissingFileAsUnavailable(t *testing.T) {
+	directory := t.TempDir()
+	_, err := fileArtifactStore{root: directory}.Open(filepath.Join(directory, "missing.smp"))
+
+	assert.Equal(t, errArtifactUnavailable, errors.Cause(err))
+}
+
+func TestHttpArtifactStoreReportsFailedResponseAsUnavailable(t *testing.T) {
+	store := &httpArtifactStore{get: func(url string) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusNotFound, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	}}
+
+	_, err := store.Open("http://example.com")
+
+	assert.Equal(t, errArtifactUnavailable, errors.Cause(err))
+}
+
+func TestArtifactStoreFailsForS3WithoutConfiguration(t *testing.T) {
+	store, err := NewArtifactStore(Configuration{})
+	assert.NoError(t, err)
+
+	_, err = store.Open("s3://plugins/scm-mail-plugin.smp")
+	assert.Error(t, err)
+
+	_, err = store.Open("ftp://example.com/scm-mail-plugin.smp")
+	assert.Error(t, err)
+}
+
+func TestDownloadHandlerProxiesLocalArtifactsInsteadOfRedirect(t *testing.T) {
+	directory := t.TempDir()
+	path := filepath.Join(directory, "ad-plugin.smp")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("content"), 0644))
+	plugin := Plugin{Name: "ad-plugin", Releases: []Release{{Version: "1.0", Url: path, Checksum: contentChecksum}}}
+
+	strategies, err := NewDownloadStrategies(Configuration{DownloadStrategies: map[string]string{"SCM": DownloadStrategyRedirect}})
+	assert.NoError(t, err)
+	store, err := NewArtifactStore(Configuration{ArtifactDirectory: directory})
+	assert.NoError(t, err)
+	downloadHandler := DownloadHandler{catalog: staticCatalog(NewCatalog([]Plugin{plugin}, nil)), strategies: strategies, store: store}
+
+	rr := initRouter(t, "/api/v1/download/ad-plugin/1.0", "", downloadHandler.handle)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "content", rr.Body.String())
+}
+
+func TestDownloadHandlerReturnsNotFoundForArtifactsOutsideOfArtifactDirectory(t *testing.T) {
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	assert.NoError(t, ioutil.WriteFile(outside, []byte("content"), 0644))
+	plugin := Plugin{Name: "ad-plugin", Releases: []Release{{Version: "1.0", Url: "file://" + outside, Checksum: contentChecksum}}}
+
+	store, err := NewArtifactStore(Configuration{ArtifactDirectory: t.TempDir()})
+	assert.NoError(t, err)
+	downloadHandler := DownloadHandler{catalog: staticCatalog(NewCatalog([]Plugin{plugin}, nil)), store: store}
+
+	rr := initRouter(t, "/api/v1/download/ad-plugin/1.0", "", downloadHandler.handle)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.NotContains(t, rr.Body.String(), "content")
+}
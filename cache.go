@@ -0,0 +1,182 @@
+package main
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/pkg/errors"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ArtifactCache stores plugin artifacts on disk, addressed by their sha256 checksum. If the cache grows beyond its
+// max size, the least recently used artifacts are removed.
+type ArtifactCache struct {
+	directory string
+	maxSize   int64
+
+	mutex   sync.Mutex
+	size    int64
+	lru     *list.List
+	entries map[string]*list.Element
+}
+
+type cacheEntry struct {
+	checksum string
+	size     int64
+}
+
+func NewArtifactCache(directory string, maxSize int64) (*ArtifactCache, error) {
+	err := os.MkdirAll(directory, 0755)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not create cache directory %s", directory)
+	}
+
+	cache := &ArtifactCache{
+		directory: directory,
+		maxSize:   maxSize,
+		lru:       list.New(),
+		entries:   make(map[string]*list.Element),
+	}
+
+	files, err := ioutil.ReadDir(directory)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read cache directory %s", directory)
+	}
+	// the modification time is updated on every access, so it restores the order of the last run
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime().After(files[j].ModTime())
+	})
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		if strings.HasPrefix(file.Name(), ".") {
+			// remove incomplete files of the last run
+			_ = os.Remove(filepath.Join(directory, file.Name()))
+			continue
+		}
+		cache.entries[file.Name()] = cache.lru.PushBack(&cacheEntry{checksum: file.Name(), size: file.Size()})
+		cache.size += file.Size()
+	}
+	cache.evict()
+
+	return cache, nil
+}
+
+// Get opens the cached artifact with the given checksum. The caller has to close the returned file.
+func (c *ArtifactCache) Get(checksum string) (*os.File, bool) {
+	checksum = strings.ToLower(checksum)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	element, ok := c.entries[checksum]
+	if !ok {
+		return nil, false
+	}
+	file, err := os.Open(c.path(checksum))
+	if err != nil {
+		log.Println("could not open cached artifact", checksum, err)
+		c.remove(element)
+		return nil, false
+	}
+	c.lru.MoveToFront(element)
+	now := time.Now()
+	_ = os.Chtimes(c.path(checksum), now, now)
+	return file, true
+}
+
+// Fill stores the content of the reader under the given checksum, if the content matches the checksum.
+// errChecksumMismatch is returned otherwise. The returned file contains the verified content, even if the
+// artifact was too large for the cache or was evicted in the meantime. The caller has to close the returned file.
+func (c *ArtifactCache) Fill(checksum string, r io.Reader) (*os.File, error) {
+	checksum = strings.ToLower(checksum)
+
+	temp, err := ioutil.TempFile(c.directory, "."+checksum+"-")
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create temporary file in cache")
+	}
+	defer func() {
+		_ = os.Remove(temp.Name())
+	}()
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(temp, hash), r)
+	closeErr := temp.Close()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not write artifact to cache")
+	}
+	if closeErr != nil {
+		return nil, errors.Wrap(closeErr, "could not write artifact to cache")
+	}
+	if hex.EncodeToString(hash.Sum(nil)) != checksum {
+		return nil, errChecksumMismatch
+	}
+
+	// the file is opened before it is moved, so that it stays readable if it is removed from the cache
+	file, err := os.Open(temp.Name())
+	if err != nil {
+		return nil, errors.Wrap(err, "could not open artifact in cache")
+	}
+
+	if size > c.maxSize {
+		log.Println("artifact", checksum, "with", size, "bytes is larger than the cache, skip caching")
+		return file, nil
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	err = os.Rename(temp.Name(), c.path(checksum))
+	if err != nil {
+		_ = file.Close()
+		return nil, errors.Wrap(err, "could not move artifact into cache")
+	}
+	if element, ok := c.entries[checksum]; ok {
+		c.size -= element.Value.(*cacheEntry).size
+		c.lru.Remove(element)
+	}
+	c.entries[checksum] = c.lru.PushFront(&cacheEntry{checksum: checksum, size: size})
+	c.size += size
+	c.evict()
+	return file, nil
+}
+
+func (c *ArtifactCache) evict() {
+	for c.size > c.maxSize && c.lru.Len() > 0 {
+		element := c.lru.Back()
+		log.Println("evicting artifact", element.Value.(*cacheEntry).checksum, "from cache")
+		c.remove(element)
+	}
+}
+
+func (c *ArtifactCache) remove(element *list.Element) {
+	entry := element.Value.(*cacheEntry)
+	c.lru.Remove(element)
+	delete(c.entries, entry.checksum)
+	c.size -= entry.size
+	err := os.Remove(c.path(entry.checksum))
+	if err != nil && !os.IsNotExist(err) {
+		log.Println("could not remove cached artifact", entry.checksum, err)
+	}
+}
+
+var sha256Pattern = regexp.MustCompile("^[a-fA-F0-9]{64}$")
+
+// isSha256Checksum returns true, if the value is a hex encoded sha256 checksum and can be used as cache key.
+func isSha256Checksum(value string) bool {
+	return sha256Pattern.MatchString(value)
+}
+
+func (c *ArtifactCache) path(checksum string) string {
+	return filepath.Join(c.directory, checksum)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func checksumOf(content string) string {
+	checksum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(checksum[:])
+}
+
+func readCached(t *testing.T, cache *ArtifactCache, checksum string) string {
+	file, ok := cache.Get(checksum)
+	assert.True(t, ok)
+	defer func() {
+		_ = file.Close()
+	}()
+	content, err := ioutil.ReadAll(file)
+	assert.NoError(t, err)
+	return string(content)
+}
+
+func fillCached(t *testing.T, cache *ArtifactCache, checksum string, content string) {
+	file, err := cache.Fill(checksum, strings.NewReader(content))
+	assert.NoError(t, err)
+	assert.NoError(t, file.Close())
+}
+
+func TestArtifactCacheFillAndGet(t *testing.T) {
+	cache, err := NewArtifactCache(t.TempDir(), 100)
+	assert.NoError(t, err)
+
+	_, ok := cache.Get(contentChecksum)
+	assert.False(t, ok)
+
+	fillCached(t, cache, contentChecksum, "content")
+	assert.Equal(t, "content", readCached(t, cache, contentChecksum))
+}
+
+func TestArtifactCacheRejectsContentWithWrongChecksum(t *testing.T) {
+	directory := t.TempDir()
+	cache, err := NewArtifactCache(directory, 100)
+	assert.NoError(t, err)
+
+	_, err = cache.Fill(contentChecksum, strings.NewReader("tampered"))
+
+	assert.Equal(t, errChecksumMismatch, err)
+	_, ok := cache.Get(contentChecksum)
+	assert.False(t, ok)
+	files, _ := ioutil.ReadDir(directory)
+	assert.Empty(t, files)
+}
+
+func TestArtifactCacheEvictsLeastRecentlyUsedArtifacts(t *testing.T) {
+	cache, err := NewArtifactCache(t.TempDir(), 10)
+	assert.NoError(t, err)
+
+	fillCached(t, cache, checksumOf("aaaa"), "aaaa")
+	fillCached(t, cache, checksumOf("bbbb"), "bbbb")
+	readCached(t, cache, checksumOf("aaaa"))
+	fillCached(t, cache, checksumOf("cccc"), "cccc")
+
+	_, ok := cache.Get(checksumOf("bbbb"))
+	assert.False(t, ok)
+	assert.Equal(t, "aaaa", readCached(t, cache, checksumOf("aaaa")))
+	assert.Equal(t, "cccc", readCached(t, cache, checksumOf("cccc")))
+}
+
+func TestArtifactCacheDoesNotStoreArtifactsLargerThanTheCache(t *testing.T) {
+	directory := t.TempDir()
+	cache, err := NewArtifactCache(directory, 4)
+	assert.NoError(t, err)
+	fillCached(t, cache, checksumOf("aaaa"), "aaaa")
+
+	file, err := cache.Fill(contentChecksum, strings.NewReader("content"))
+
+	assert.NoError(t, err)
+	content, err := ioutil.ReadAll(file)
+	assert.NoError(t, err)
+	assert.NoError(t, file.Close())
+	assert.Equal(t, "content", string(content))
+	_, ok := cache.Get(contentChecksum)
+	assert.False(t, ok)
+	assert.Equal(t, "aaaa", readCached(t, cache, checksumOf("aaaa")))
+	files, _ := ioutil.ReadDir(directory)
+	assert.Len(t, files, 1)
+}
+
+func TestArtifactCacheRestoresEntriesFromDirectory(t *testing.T) {
+	directory := t.TempDir()
+	old := time.Now().Add(-time.Hour)
+	for _, content := range []string{"aaaa", "bbbb"} {
+		path := filepath.Join(directory, checksumOf(content))
+		assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+		if content == "aaaa" {
+			assert.NoError(t, os.Chtimes(path, old, old))
+		}
+	}
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(directory, ".incomplete"), []byte("x"), 0644))
+
+	cache, err := NewArtifactCache(directory, 4)
+	assert.NoError(t, err)
+
+	_, ok := cache.Get(checksumOf("aaaa"))
+	assert.False(t, ok)
+	assert.Equal(t, "bbbb", readCached(t, cache, checksumOf("bbbb")))
+	_, err = os.Stat(filepath.Join(directory, ".incomplete"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestIsSha256Checksum(t *testing.T) {
+	assert.True(t, isSha256Checksum(contentChecksum))
+	assert.False(t, isSha256Checksum("abc"))
+	assert.False(t, isSha256Checksum("../../etc/passwd"))
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"hash/fnv"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	catalogReloadCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "scm_plugin_center_catalog_reloads",
+		Help: "Total number of catalog reloads",
+	}, []string{
+		"successful",
+	})
+
+	catalogProblemsGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "scm_plugin_center_catalog_problems",
+		Help: "Number of problems found while scanning the current catalog",
+	})
+)
+
+// Catalog is an immutable snapshot of all plugins and plugin sets.
+type Catalog struct {
+	Plugins       []Plugin
+	PluginSets    []PluginSet
+	Problems      Problems
+	pluginsByName map[string]Plugin
+}
+
+func NewCatalog(plugins []Plugin, pluginSets []PluginSet) *Catalog {
+	return &Catalog{
+		Plugins:       plugins,
+		PluginSets:    pluginSets,
+		pluginsByName: createMap(plugins),
+	}
+}
+
+func createMap(plugins []Plugin) map[string]Plugin {
+	m := make(map[string]Plugin)
+	for _, plugin := range plugins {
+		m[plugin.Name] = plugin
+	}
+	return m
+}
+
+func (c *Catalog) FindPlugin(name string) (Plugin, bool) {
+	plugin, ok := c.pluginsByName[name]
+	return plugin, ok
+}
+
+func (c *Catalog) FindPluginSet(id string) (PluginSet, bool) {
+	for _, pluginSet := range c.PluginSets {
+		if pluginSet.Id == id {
+			return pluginSet, true
+		}
+	}
+	return PluginSet{}, false
+}
+
+type CatalogLoader func() (*Catalog, error)
+
+func NewDirectoryCatalogLoader(configuration Configuration) CatalogLoader {
+	return func() (*Catalog, error) {
+		plugins, problems, err := scanDirectory(configuration.DescriptorDirectory)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not parse plugins")
+		}
+
+		pluginSets, pluginSetProblems, err := scanPluginSetsDirectory(configuration.PluginSetsDirectory)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not parse plugin sets")
+		}
+		problems = append(problems, pluginSetProblems...)
+
+		checkPluginSetMembers(&problems, pluginSets, plugins)
+
+		if len(problems) > 0 {
+			if configuration.ScanPolicy == ScanPolicyStrict {
+				return nil, errors.Wrap(problems, "could not parse plugins")
+			}
+			for _, problem := range problems {
+				log.Println("problem in descriptor:", problem)
+			}
+		}
+
+		catalog := NewCatalog(plugins, pluginSets)
+		catalog.Problems = problems
+		return catalog, nil
+	}
+}
+
+// CatalogHolder serves the current catalog and swaps it atomically on reload,
+// so that running requests keep working on the snapshot they started with.
+type CatalogHolder struct {
+	catalog   atomic.Value
+	load      CatalogLoader
+	mutex     sync.Mutex
+	lastError error
+}
+
+func NewCatalogHolder(load CatalogLoader) (*CatalogHolder, error) {
+	catalog, err := load()
+	if err != nil {
+		return nil, err
+	}
+	holder := &CatalogHolder{load: load}
+	holder.store(catalog)
+	return holder, nil
+}
+
+func (h *CatalogHolder) Get() *Catalog {
+	return h.catalog.Load().(*Catalog)
+}
+
+// Reload rescans the catalog and returns the changes compared to the previous one.
+// If the scan fails, the last good catalog is kept.
+func (h *CatalogHolder) Reload() (CatalogDiff, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	catalog, err := h.load()
+	catalogReloadCounter.WithLabelValues(strconv.FormatBool(err == nil)).Inc()
+	h.lastError = err
+	if err != nil {
+		return CatalogDiff{}, err
+	}
+	diff := diffCatalogs(h.Get(), catalog)
+	h.store(catalog)
+	return diff, nil
+}
+
+func (h *CatalogHolder) store(catalog *Catalog) {
+	h.catalog.Store(catalog)
+	catalogProblemsGauge.Set(float64(len(catalog.Problems)))
+}
+
+func (h *CatalogHolder) LastError() error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.lastError
+}
+
+// Watch polls the given directories and reloads the catalog whenever one of them changes.
+// The returned function stops polling and waits until a running reload has finished,
+// it is safe to call it more than once.
+func (h *CatalogHolder) Watch(interval time.Duration, directories ...string) func() {
+	last := fingerprint(directories)
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			current := fingerprint(directories)
+			if current == last {
+				continue
+			}
+			last = current
+
+			log.Println("detected changes in catalog directories, reloading catalog")
+			diff, err := h.Reload()
+			if err != nil {
+				log.Println("failed to reload catalog, keep serving the last good one:", err)
+			} else {
+				log.Println("catalog reloaded:", diff)
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+		})
+		<-stopped
+	}
+}
+
+func fingerprint(directories []string) uint64 {
+	hash := fnv.New64a()
+	for _, directory := range directories {
+		_ = filepath.Walk(directory, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				_, _ = hash.Write([]byte(path + ":error"))
+				return nil
+			}
+			_, _ = hash.Write([]byte(path))
+			_, _ = hash.Write([]byte(strconv.FormatInt(info.Size(), 10)))
+			_, _ = hash.Write([]byte(strconv.FormatInt(info.ModTime().UnixNano(), 10)))
+			return nil
+		})
+	}
+	return hash.Sum64()
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+type ReleaseDiff struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+}
+
+type CatalogDiff struct {
+	PluginsAdded      []string               `json:"pluginsAdded"`
+	PluginsRemoved    []string               `json:"pluginsRemoved"`
+	Releases          map[string]ReleaseDiff `json:"releases"`
+	PluginSetsAdded   []string               `json:"pluginSetsAdded"`
+	PluginSetsRemoved []string               `json:"pluginSetsRemoved"`
+	PluginSetsChanged []string               `json:"pluginSetsChanged"`
+}
+
+func (d CatalogDiff) String() string {
+	return fmt.Sprintf(
+		"%d plugins added, %d plugins removed, releases of %d plugins changed, %d plugin sets added, %d plugin sets removed, %d plugin sets changed",
+		len(d.PluginsAdded), len(d.PluginsRemoved), len(d.Releases),
+		len(d.PluginSetsAdded), len(d.PluginSetsRemoved), len(d.PluginSetsChanged),
+	)
+}
+
+func diffCatalogs(oldCatalog *Catalog, newCatalog *Catalog) CatalogDiff {
+	diff := CatalogDiff{
+		PluginsAdded:      []string{},
+		PluginsRemoved:    []string{},
+		Releases:          map[string]ReleaseDiff{},
+		PluginSetsAdded:   []string{},
+		PluginSetsRemoved: []string{},
+		PluginSetsChanged: []string{},
+	}
+
+	for _, plugin := range newCatalog.Plugins {
+		oldPlugin, ok := oldCatalog.FindPlugin(plugin.Name)
+		if !ok {
+			diff.PluginsAdded = append(diff.PluginsAdded, plugin.Name)
+		}
+		releaseDiff := diffReleases(oldPlugin.Releases, plugin.Releases)
+		if len(releaseDiff.Added) > 0 || len(releaseDiff.Removed) > 0 {
+			diff.Releases[plugin.Name] = releaseDiff
+		}
+	}
+
+	for _, plugin := range oldCatalog.Plugins {
+		if _, ok := newCatalog.FindPlugin(plugin.Name); !ok {
+			diff.PluginsRemoved = append(diff.PluginsRemoved, plugin.Name)
+			diff.Releases[plugin.Name] = diffReleases(plugin.Releases, nil)
+		}
+	}
+
+	oldPluginSets := createPluginSetMap(oldCatalog.PluginSets)
+	newPluginSets := createPluginSetMap(newCatalog.PluginSets)
+	for id, pluginSet := range newPluginSets {
+		oldPluginSet, ok := oldPluginSets[id]
+		if !ok {
+			diff.PluginSetsAdded = append(diff.PluginSetsAdded, id)
+		} else if !pluginSetsEqual(oldPluginSet, pluginSet) {
+			diff.PluginSetsChanged = append(diff.PluginSetsChanged, id)
+		}
+	}
+	for id := range oldPluginSets {
+		if _, ok := newPluginSets[id]; !ok {
+			diff.PluginSetsRemoved = append(diff.PluginSetsRemoved, id)
+		}
+	}
+
+	sort.Strings(diff.PluginsAdded)
+	sort.Strings(diff.PluginsRemoved)
+	sort.Strings(diff.PluginSetsAdded)
+	sort.Strings(diff.PluginSetsRemoved)
+	sort.Strings(diff.PluginSetsChanged)
+
+	return diff
+}
+
+func diffReleases(oldReleases []Release, newReleases []Release) ReleaseDiff {
+	diff := ReleaseDiff{Added: []string{}, Removed: []string{}}
+
+	oldVersions := releaseVersions(oldReleases)
+	newVersions := releaseVersions(newReleases)
+	for _, release := range newReleases {
+		if !oldVersions[release.Version] {
+			diff.Added = append(diff.Added, release.Version)
+		}
+	}
+	for _, release := range oldReleases {
+		if !newVersions[release.Version] {
+			diff.Removed = append(diff.Removed, release.Version)
+		}
+	}
+	return diff
+}
+
+func releaseVersions(releases []Release) map[string]bool {
+	versions := make(map[string]bool)
+	for _, release := range releases {
+		versions[release.Version] = true
+	}
+	return versions
+}
+
+func createPluginSetMap(pluginSets []PluginSet) map[string]PluginSet {
+	m := make(map[string]PluginSet)
+	for _, pluginSet := range pluginSets {
+		m[pluginSet.Id] = pluginSet
+	}
+	return m
+}
+
+func pluginSetsEqual(a PluginSet, b PluginSet) bool {
+	// the parsed range is a function, so we have to compare the raw value
+	if a.Versions.Value != b.Versions.Value {
+		return false
+	}
+	a.Versions = VersionRange{}
+	b.Versions = VersionRange{}
+	return reflect.DeepEqual(a, b)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDiffCatalogsWithoutChanges(t *testing.T) {
+	diff := diffCatalogs(NewCatalog(testData, testDataPluginSets), NewCatalog(testData, testDataPluginSets))
+
+	assert.Empty(t, diff.PluginsAdded)
+	assert.Empty(t, diff.PluginsRemoved)
+	assert.Empty(t, diff.Releases)
+	assert.Empty(t, diff.PluginSetsAdded)
+	assert.Empty(t, diff.PluginSetsRemoved)
+	assert.Empty(t, diff.PluginSetsChanged)
+}
+
+func TestDiffCatalogsWithAddedAndRemovedPlugins(t *testing.T) {
+	diff := diffCatalogs(NewCatalog(testData[:1], nil), NewCatalog(testData[1:], nil))
+
+	assert.Equal(t, []string{"ad-plugin"}, diff.PluginsAdded)
+	assert.Equal(t, []string{"ssh-plugin"}, diff.PluginsRemoved)
+	assert.Equal(t, []string{"1.0"}, diff.Releases["ad-plugin"].Added)
+	assert.Equal(t, []string{"2.0", "1.1", "0.1"}, diff.Releases["ssh-plugin"].Removed)
+}
+
+func TestDiffCatalogsWithChangedReleases(t *testing.T) {
+	plugin := testData[0]
+	plugin.Releases = append([]Release{{Version: "2.1"}}, plugin.Releases[1:]...)
+
+	diff := diffCatalogs(NewCatalog(testData[:1], nil), NewCatalog([]Plugin{plugin}, nil))
+
+	assert.Empty(t, diff.PluginsAdded)
+	assert.Empty(t, diff.PluginsRemoved)
+	assert.Equal(t, []string{"2.1"}, diff.Releases["ssh-plugin"].Added)
+	assert.Equal(t, []string{"2.0"}, diff.Releases["ssh-plugin"].Removed)
+}
+
+func TestDiffCatalogsWithChangedPluginSets(t *testing.T) {
+	changed := testDataPluginSets[0]
+	changed.Versions = MustParseVersionRange(">=2.1.0")
+	added := PluginSet{Id: "new-set"}
+
+	diff := diffCatalogs(
+		NewCatalog(nil, testDataPluginSets),
+		NewCatalog(nil, []PluginSet{changed, added}),
+	)
+
+	assert.Equal(t, []string{"new-set"}, diff.PluginSetsAdded)
+	assert.Equal(t, []string{"administration-and-management"}, diff.PluginSetsRemoved)
+	assert.Equal(t, []string{"plug-and-play"}, diff.PluginSetsChanged)
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCatalogHolderLoadsCatalogFromDirectories(t *testing.T) {
+	holder, err := NewCatalogHolder(NewDirectoryCatalogLoader(readConfiguration()))
+	assert.NoError(t, err)
+
+	catalog := holder.Get()
+	assert.Len(t, catalog.Plugins, 4)
+	assert.Len(t, catalog.PluginSets, 2)
+
+	plugin, ok := catalog.FindPlugin("scm-cas-plugin")
+	assert.True(t, ok)
+	assert.Equal(t, "CAS", plugin.DisplayName)
+}
+
+func TestCatalogHolderFailsForMissingDirectory(t *testing.T) {
+	_, err := NewCatalogHolder(NewDirectoryCatalogLoader(Configuration{DescriptorDirectory: "no/such/folder"}))
+	assert.Error(t, err)
+}
+
+func TestCatalogLoaderSkipsBrokenDescriptorsInLenientMode(t *testing.T) {
+	holder, err := NewCatalogHolder(NewDirectoryCatalogLoader(Configuration{
+		DescriptorDirectory: "resources/test/validate/broken/plugins",
+		PluginSetsDirectory: "resources/test/plugin-sets/proper-plugin-sets",
+		ScanPolicy:          ScanPolicyLenient,
+	}))
+	assert.NoError(t, err)
+
+	catalog := holder.Get()
+	assert.Len(t, catalog.Plugins, 1)
+	assert.NotEmpty(t, catalog.Problems)
+}
+
+func TestCatalogLoaderFailsForBrokenDescriptorsInStrictMode(t *testing.T) {
+	_, err := NewCatalogHolder(NewDirectoryCatalogLoader(Configuration{
+		DescriptorDirectory: "resources/test/validate/broken/plugins",
+		PluginSetsDirectory: "resources/test/plugin-sets/proper-plugin-sets",
+		ScanPolicy:          ScanPolicyStrict,
+	}))
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "next is not a valid version")
+}
+
+func TestCatalogHolderReloadSwapsCatalog(t *testing.T) {
+	catalogs := []*Catalog{NewCatalog(testData, nil), NewCatalog(testData[:1], nil)}
+	calls := 0
+	holder, err := NewCatalogHolder(func() (*Catalog, error) {
+		catalog := catalogs[calls]
+		calls++
+		return catalog, nil
+	})
+	assert.NoError(t, err)
+
+	snapshot := holder.Get()
+	_, err = holder.Reload()
+
+	assert.NoError(t, err)
+	assert.Len(t, holder.Get().Plugins, 1)
+	assert.Len(t, snapshot.Plugins, 2)
+}
+
+func TestCatalogHolderKeepsLastGoodCatalogOnFailedReload(t *testing.T) {
+	fail := false
+	holder, err := NewCatalogHolder(func() (*Catalog, error) {
+		if fail {
+			return nil, errors.New("broken release")
+		}
+		return NewCatalog(testData, nil), nil
+	})
+	assert.NoError(t, err)
+
+	fail = true
+	_, err = holder.Reload()
+
+	assert.Error(t, err)
+	assert.Equal(t, err, holder.LastError())
+	assert.Len(t, holder.Get().Plugins, 2)
+}
+
+func TestCatalogHolderWatchReloadsOnChange(t *testing.T) {
+	directory := t.TempDir()
+	writePluginYml(t, directory, "scm-first-plugin")
+
+	holder, err := NewCatalogHolder(NewDirectoryCatalogLoader(Configuration{
+		DescriptorDirectory: directory,
+		PluginSetsDirectory: "resources/test/plugin-sets/proper-plugin-sets",
+	}))
+	assert.NoError(t, err)
+	assert.Len(t, holder.Get().Plugins, 1)
+
+	stop := holder.Watch(10*time.Millisecond, directory)
+	defer stop()
+	writePluginYml(t, directory, "scm-second-plugin")
+
+	assert.Eventually(t, func() bool {
+		return len(holder.Get().Plugins) == 2
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestCatalogHolderWatchStopsPolling(t *testing.T) {
+	directory := t.TempDir()
+	writePluginYml(t, directory, "scm-first-plugin")
+
+	holder, err := NewCatalogHolder(NewDirectoryCatalogLoader(Configuration{
+		DescriptorDirectory: directory,
+		PluginSetsDirectory: "resources/test/plugin-sets/proper-plugin-sets",
+	}))
+	assert.NoError(t, err)
+
+	stop := holder.Watch(10*time.Millisecond, directory)
+	stop()
+	stop()
+	writePluginYml(t, directory, "scm-second-plugin")
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, holder.Get().Plugins, 1)
+}
+
+func writePluginYml(t *testing.T, directory string, name string) {
+	pluginDirectory := filepath.Join(directory, name)
+	assert.NoError(t, os.MkdirAll(pluginDirectory, 0755))
+	content := []byte("name: " + name + "\n")
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(pluginDirectory, "plugin.yml"), content, 0644))
+}
+
+func TestCatalogLoaderReportsUnknownPluginSetMembers(t *testing.T) {
+	holder, err := NewCatalogHolder(NewDirectoryCatalogLoader(Configuration{
+		DescriptorDirectory: "resources/test/plugins",
+		PluginSetsDirectory: "resources/test/plugin-sets/proper-plugin-sets",
+		ScanPolicy:          ScanPolicyLenient,
+	}))
+	assert.NoError(t, err)
+
+	assert.Contains(t, holder.Get().Problems, Problem{
+		File:    "resources/test/plugin-sets/proper-plugin-sets/plug-and-play/plugins.yml",
+		Line:    5,
+		Field:   "plugins",
+		Message: "plugin scm-landingpage-plugin does not exist",
+	})
+}
+
+func TestCatalogLoaderFailsForUnknownPluginSetMembersInStrictMode(t *testing.T) {
+	_, err := NewCatalogHolder(NewDirectoryCatalogLoader(Configuration{
+		DescriptorDirectory: "resources/test/plugins",
+		PluginSetsDirectory: "resources/test/plugin-sets/proper-plugin-sets",
+		ScanPolicy:          ScanPolicyStrict,
+	}))
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "plugin scm-landingpage-plugin does not exist")
+}
+
+func TestCatalogLoaderSkipsBrokenPluginSetsInLenientMode(t *testing.T) {
+	directory := copyPluginSets(t, "resources/test/plugin-sets/proper-plugin-sets", "resources/test/plugin-sets/plugin-sets-no-id/plugin-set")
+
+	holder, err := NewCatalogHolder(NewDirectoryCatalogLoader(Configuration{
+		DescriptorDirectory: "resources/test/plugins",
+		PluginSetsDirectory: directory,
+		ScanPolicy:          ScanPolicyLenient,
+	}))
+	assert.NoError(t, err)
+
+	assert.Len(t, holder.Get().PluginSets, 2)
+	assert.Contains(t, holder.Get().Problems.ForFile(filepath.Join(directory, "plugin-set")).Error(), "id is missing")
+}
+
+func TestCatalogLoaderFailsForBrokenPluginSetsInStrictMode(t *testing.T) {
+	directory := copyPluginSets(t, "resources/test/plugin-sets/proper-plugin-sets", "resources/test/plugin-sets/plugin-sets-no-id/plugin-set")
+
+	_, err := NewCatalogHolder(NewDirectoryCatalogLoader(Configuration{
+		DescriptorDirectory: "resources/test/plugins",
+		PluginSetsDirectory: directory,
+		ScanPolicy:          ScanPolicyStrict,
+	}))
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "id is missing")
+}
+
+// copyPluginSets copies all plugin sets of the directory and the additional plugin set into a temporary directory.
+func copyPluginSets(t *testing.T, directory string, pluginSet string) string {
+	target := t.TempDir()
+	pluginSetDirectories, err := ioutil.ReadDir(directory)
+	assert.NoError(t, err)
+	for _, pluginSetDirectory := range pluginSetDirectories {
+		copyDirectory(t, filepath.Join(directory, pluginSetDirectory.Name()), filepath.Join(target, pluginSetDirectory.Name()))
+	}
+	copyDirectory(t, pluginSet, filepath.Join(target, filepath.Base(pluginSet)))
+	return target
+}
+
+func copyDirectory(t *testing.T, source string, target string) {
+	assert.NoError(t, os.MkdirAll(target, 0755))
+	files, err := ioutil.ReadDir(source)
+	assert.NoError(t, err)
+	for _, file := range files {
+		data, err := ioutil.ReadFile(filepath.Join(source, file.Name()))
+		assert.NoError(t, err)
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(target, file.Name()), data, 0644))
+	}
+}
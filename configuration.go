@@ -6,15 +6,33 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"time"
 )
 
 type Configuration struct {
-	DescriptorDirectory string `yaml:"descriptor-directory" envconfig:"CONFIG_DESCRIPTOR_DIRECTORY"`
-	PluginSetsDirectory string `yaml:"plugin-sets-directory" envconfig:"CONFIG_PLUGIN_SETS_DIRECTORY"`
-	Port                int    `yaml:"port" envconfig:"CONFIG_PORT" default:"8000"`
-	Oidc                OidcConfiguration
+	DescriptorDirectory  string            `yaml:"descriptor-directory" envconfig:"CONFIG_DESCRIPTOR_DIRECTORY"`
+	PluginSetsDirectory  string            `yaml:"plugin-sets-directory" envconfig:"CONFIG_PLUGIN_SETS_DIRECTORY"`
+	Port                 int               `yaml:"port" envconfig:"CONFIG_PORT" default:"8000"`
+	ReloadInterval       time.Duration     `yaml:"reload-interval" envconfig:"CONFIG_RELOAD_INTERVAL"`
+	AdminToken           string            `yaml:"admin-token" envconfig:"CONFIG_ADMIN_TOKEN"`
+	ScanPolicy           string            `yaml:"scan-policy" envconfig:"CONFIG_SCAN_POLICY"`
+	ArtifactDirectory    string            `yaml:"artifact-directory" envconfig:"CONFIG_ARTIFACT_DIRECTORY"`
+	CacheDirectory       string            `yaml:"cache-directory" envconfig:"CONFIG_CACHE_DIRECTORY"`
+	CacheSize            int64             `yaml:"cache-size" envconfig:"CONFIG_CACHE_SIZE"`
+	DownloadStrategies   map[string]string `yaml:"download-strategies" envconfig:"CONFIG_DOWNLOAD_STRATEGIES"`
+	DownloadSigningKey   string            `yaml:"download-signing-key" envconfig:"CONFIG_DOWNLOAD_SIGNING_KEY"`
+	DownloadSignedUrlTtl time.Duration     `yaml:"download-signed-url-ttl" envconfig:"CONFIG_DOWNLOAD_SIGNED_URL_TTL"`
+	Oidc                 OidcConfiguration
+	S3                   S3Configuration
 }
 
+const (
+	// ScanPolicyStrict refuses to load a catalog with broken descriptors
+	ScanPolicyStrict = "strict"
+	// ScanPolicyLenient skips broken descriptors and reports them as diagnostics
+	ScanPolicyLenient = "lenient"
+)
+
 type OidcConfiguration struct {
 	Issuer       string `yaml:"issuer" envconfig:"CONFIG_OIDC_ISSUER"`
 	ClientID     string `yaml:"client-id" envconfig:"CONFIG_OIDC_CLIENT_ID"`
@@ -27,16 +45,35 @@ func (oc OidcConfiguration) IsEnabled() bool {
 	return oc.Issuer != ""
 }
 
+type S3Configuration struct {
+	Endpoint  string `yaml:"endpoint" envconfig:"CONFIG_S3_ENDPOINT"`
+	Region    string `yaml:"region" envconfig:"CONFIG_S3_REGION"`
+	AccessKey string `yaml:"access-key" envconfig:"CONFIG_S3_ACCESS_KEY"`
+	SecretKey string `yaml:"secret-key" envconfig:"CONFIG_S3_SECRET_KEY"`
+}
+
+func (sc S3Configuration) IsEnabled() bool {
+	return sc.Endpoint != ""
+}
+
 func readConfiguration() Configuration {
 	configPath := os.Getenv("CONFIG")
 	if configPath == "" {
 		configPath = "config.yaml"
 	}
 
-	config := Configuration{}
+	config := Configuration{
+		ReloadInterval:       30 * time.Second,
+		ScanPolicy:           ScanPolicyLenient,
+		CacheSize:            10 * 1024 * 1024 * 1024,
+		DownloadSignedUrlTtl: 5 * time.Minute,
+	}
 	config.Oidc = OidcConfiguration{
 		development: false,
 	}
+	config.S3 = S3Configuration{
+		Region: "us-east-1",
+	}
 
 	exists, err := exists(configPath)
 	if err != nil {
@@ -60,6 +97,10 @@ func readConfiguration() Configuration {
 		log.Fatalf("failed to read configuration from environment: %v", err)
 	}
 
+	if config.ScanPolicy != ScanPolicyStrict && config.ScanPolicy != ScanPolicyLenient {
+		log.Fatalf("unknown scan policy %s, use %s or %s", config.ScanPolicy, ScanPolicyStrict, ScanPolicyLenient)
+	}
+
 	return config
 }
 
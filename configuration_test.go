@@ -73,4 +73,14 @@ func TestReadConfigurationFromConfigYamlAndEnvironment(t *testing.T) {
 func TestReadConfigurationAndUseDefaults(t *testing.T) {
 	config := readConfiguration()
 	assert.Equal(t, 8000, config.Port)
+	assert.Equal(t, ScanPolicyLenient, config.ScanPolicy)
+	assert.Equal(t, "", config.CacheDirectory)
+	assert.Equal(t, int64(10*1024*1024*1024), config.CacheSize)
+}
+
+func TestReadConfigurationWithScanPolicyFromEnv(t *testing.T) {
+	t.Setenv("CONFIG_SCAN_POLICY", "strict")
+
+	config := readConfiguration()
+	assert.Equal(t, ScanPolicyStrict, config.ScanPolicy)
 }
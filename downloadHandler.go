@@ -1,13 +1,19 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"io"
 	"log"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
 )
 
 type UrlGenerator struct {
@@ -35,9 +41,19 @@ func (u *UrlGenerator) DownloadUrl(plugin Plugin, version string) string {
 	return fmt.Sprintf("%v://%v/api/v1/download/%v/%v", u.protocol, u.host, plugin.Name, version)
 }
 
+func (u *UrlGenerator) Url(path string) string {
+	return fmt.Sprintf("%v://%v%v", u.protocol, u.host, path)
+}
+
+func (u *UrlGenerator) PluginSetImageUrl(pluginSet PluginSet, name string) string {
+	return fmt.Sprintf("%v://%v/api/v1/plugin-sets/%v/images/%v", u.protocol, u.host, pluginSet.Id, name)
+}
+
 type DownloadHandler struct {
-	plugins        map[string]Plugin
-	downloadPlugin func(url string) (resp *http.Response, err error)
+	catalog    *CatalogHolder
+	cache      *ArtifactCache
+	strategies DownloadStrategies
+	store      ArtifactStore
 }
 
 var (
@@ -47,26 +63,45 @@ var (
 	}, []string{
 		"plugin", "version",
 	})
+	checksumMismatchCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "scm_plugin_center_download_checksum_mismatches",
+		Help: "Total number of downloads which were aborted, because the checksum did not match",
+	}, []string{
+		"plugin", "version",
+	})
+	cacheHitCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "scm_plugin_center_download_cache_hits",
+		Help: "Total number of downloads served from the artifact cache",
+	}, []string{
+		"plugin", "version",
+	})
+	cacheMissCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "scm_plugin_center_download_cache_misses",
+		Help: "Total number of downloads which were not found in the artifact cache",
+	}, []string{
+		"plugin", "version",
+	})
+	cacheBytesCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "scm_plugin_center_download_cache_bytes_served",
+		Help: "Total number of bytes served from the artifact cache",
+	})
 )
 
-func NewDownloadHandler(plugins []Plugin) http.HandlerFunc {
-	handler := DownloadHandler{plugins: createMap(plugins), downloadPlugin: http.Get}
-	return handler.handle
-}
+const downloadBufferSize = 32 * 1024
 
-func createMap(plugins []Plugin) map[string]Plugin {
-	m := make(map[string]Plugin)
-	for _, plugin := range plugins {
-		m[plugin.Name] = plugin
-	}
-	return m
+// NewDownloadHandler creates a handler which serves plugin downloads with the strategy of the plugin type.
+// If plugins are proxied and a cache is passed, artifacts are served from and stored in the cache,
+// otherwise they are streamed from the store.
+func NewDownloadHandler(catalog *CatalogHolder, store ArtifactStore, cache *ArtifactCache, strategies DownloadStrategies) http.HandlerFunc {
+	handler := DownloadHandler{catalog: catalog, store: store, cache: cache, strategies: strategies}
+	return handler.handle
 }
 
 func (h *DownloadHandler) handle(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 
 	pluginName := vars["plugin"]
-	plugin, ok := h.plugins[pluginName]
+	plugin, ok := h.catalog.Get().FindPlugin(pluginName)
 	if !ok {
 		msg := fmt.Sprintf("no plugin found for name %s", pluginName)
 		log.Println(msg)
@@ -93,36 +128,223 @@ func (h *DownloadHandler) handle(w http.ResponseWriter, r *http.Request) {
 
 	log.Println("found release for plugin", pluginName, "and version", pluginVersion, ":", release.Url)
 
+	etag := createETag(release)
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
 	downloadCounter.WithLabelValues(
 		pluginName,
 		pluginVersion,
 	).Inc()
 
-	h.copyHttpStream(release, pluginName, pluginVersion, w)
+	strategy := h.strategies.For(plugin)
+	if strategy != DownloadStrategyProxy && !isHttpUrl(release.Url) {
+		// clients are only able to follow redirects to http urls
+		strategy = DownloadStrategyProxy
+	}
+
+	switch strategy {
+	case DownloadStrategyRedirect:
+		http.Redirect(w, r, release.Url, http.StatusFound)
+		return
+	case DownloadStrategySigned:
+		signedUrl, err := h.strategies.signer.Sign(release.Url)
+		if err != nil {
+			log.Println("could not sign url for plugin", pluginName, "and version", pluginVersion, err)
+			http.Error(w, "could not create download url", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, signedUrl, http.StatusFound)
+		return
+	}
+
+	if h.cache != nil && isSha256Checksum(release.Checksum) {
+		h.serveCached(w, r, release, pluginName, pluginVersion)
+		return
+	}
+	h.copyStream(w, r, release, pluginName, pluginVersion)
+}
+
+func (h *DownloadHandler) serveCached(w http.ResponseWriter, r *http.Request, release *Release, pluginName string, pluginVersion string) {
+	file, ok := h.cache.Get(release.Checksum)
+	if ok {
+		cacheHitCounter.WithLabelValues(pluginName, pluginVersion).Inc()
+	} else {
+		cacheMissCounter.WithLabelValues(pluginName, pluginVersion).Inc()
+		file = h.fillCache(w, release, pluginName, pluginVersion)
+		if file == nil {
+			return
+		}
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	info, err := file.Stat()
+	if err != nil {
+		log.Println("could not stat cached artifact of plugin", pluginName, "and version", pluginVersion, err)
+		http.Error(w, "could not read plugin from cache", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Add("Content-Disposition", `attachment; filename="`+pluginName+`.smp"`)
+	http.ServeContent(&countingResponseWriter{ResponseWriter: w}, r, pluginName+".smp", info.ModTime(), file)
+}
+
+// fillCache stores the artifact of the release in the cache and returns the verified content or writes an error
+// response and returns nil, if the artifact could not be stored.
+func (h *DownloadHandler) fillCache(w http.ResponseWriter, release *Release, pluginName string, pluginVersion string) *os.File {
+	artifact := h.openArtifact(w, release, pluginName, pluginVersion)
+	if artifact == nil {
+		return nil
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(artifact.Body)
+
+	file, err := h.cache.Fill(release.Checksum, artifact.Body)
+	if err == errChecksumMismatch {
+		log.Println("checksum of download from url", release.Url, "does not match", release.Checksum)
+		checksumMismatchCounter.WithLabelValues(pluginName, pluginVersion).Inc()
+		http.Error(w, "checksum of plugin from target does not match", http.StatusBadGateway)
+		return nil
+	}
+	if err != nil {
+		log.Println("could not store plugin", pluginName, "and version", pluginVersion, "in cache", err)
+		http.Error(w, "could not read plugin from target", http.StatusBadGateway)
+		return nil
+	}
+	return file
 }
 
-func (h *DownloadHandler) copyHttpStream(release *Release, pluginName string, pluginVersion string, w http.ResponseWriter) {
-	resp, err := h.downloadPlugin(release.Url)
+// countingResponseWriter counts the bytes written to the body for the cache metrics
+type countingResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (w *countingResponseWriter) Write(data []byte) (int, error) {
+	written, err := w.ResponseWriter.Write(data)
+	cacheBytesCounter.Add(float64(written))
+	return written, err
+}
+
+// openArtifact opens the artifact of the release or writes an error response, if the artifact is not available.
+func (h *DownloadHandler) openArtifact(w http.ResponseWriter, release *Release, pluginName string, pluginVersion string) *Artifact {
+	artifact, err := h.store.Open(release.Url)
+	if errors.Cause(err) == errArtifactNotAllowed {
+		log.Println("artifact for plugin", pluginName, "and version", pluginVersion, "is not allowed:", err)
+		http.Error(w, "plugin artifact not found", http.StatusNotFound)
+		return nil
+	}
+	if errors.Cause(err) == errArtifactUnavailable {
+		log.Println("artifact for plugin", pluginName, "and version", pluginVersion, "is not available:", err)
+		http.Error(w, "could not read plugin from target", http.StatusBadGateway)
+		return nil
+	}
 	if err != nil {
 		log.Println("error opening url for plugin", pluginName, "and version", pluginVersion, ":", release.Url, err)
 		http.Error(w, "could not read plugin from target", http.StatusServiceUnavailable)
+		return nil
+	}
+	return artifact
+}
+
+func (h *DownloadHandler) copyStream(w http.ResponseWriter, r *http.Request, release *Release, pluginName string, pluginVersion string) {
+	artifact := h.openArtifact(w, release, pluginName, pluginVersion)
+	if artifact == nil {
 		return
 	}
 	defer func(Body io.ReadCloser) {
 		_ = Body.Close()
-	}(resp.Body)
+	}(artifact.Body)
 	w.Header().Add("Content-Disposition", `attachment; filename="`+pluginName+`.smp"`)
-	written, err := io.Copy(w, resp.Body)
+	contentType := artifact.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+
+	// the complete content is always read from the target, because the checksum can only be verified for the
+	// complete content; a range only limits the bytes which are sent to the client, so the range header is not
+	// forwarded to the store (efficient ranges require the artifact cache)
+	var target io.Writer = w
+	status := http.StatusOK
+	if artifact.Size >= 0 {
+		w.Header().Set("Accept-Ranges", "bytes")
+		length := artifact.Size
+		byteRange, err := parseRange(r, w.Header().Get("ETag"), artifact.Size)
+		if err == errRangeNotSatisfiable {
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", artifact.Size))
+			http.Error(w, "requested range not satisfiable", http.StatusRequestedRangeNotSatisfiable)
+			return
+		}
+		if byteRange != nil {
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", byteRange.start, byteRange.end, artifact.Size))
+			length = byteRange.length()
+			status = http.StatusPartialContent
+			target = &rangeWriter{writer: w, byteRange: *byteRange}
+		}
+		w.Header().Set("Content-Length", strconv.FormatInt(length, 10))
+	}
+	w.WriteHeader(status)
+
+	// releases without checksum are skipped by the scanner, so every served artifact can be verified
+	written, err := copyVerified(target, artifact.Body, release.Checksum)
+	if err == errChecksumMismatch {
+		log.Println("checksum of download stream for url", release.Url, "does not match", release.Checksum, "- aborting response")
+		checksumMismatchCounter.WithLabelValues(pluginName, pluginVersion).Inc()
+		// aborts the response without completing it, so that the client does not accept the corrupted file
+		panic(http.ErrAbortHandler)
+	}
 	if err != nil {
 		log.Println("got an error copying download stream for url", release.Url, "after", written, "bytes:", err)
 	}
 }
 
-func (h *DownloadHandler) findRelease(plugin Plugin, version string) *Release {
-	for _, release := range plugin.Releases {
-		if release.Version == version {
-			return &release
+var errChecksumMismatch = errors.New("checksum mismatch")
+
+// copyVerified copies the reader to the writer and verifies the sha256 checksum of the content on the fly.
+// The last chunk is held back until the checksum is verified, so that a mismatch is detected before the
+// client has received the complete content.
+func copyVerified(w io.Writer, r io.Reader, checksum string) (int64, error) {
+	hash := sha256.New()
+	buffer := make([]byte, downloadBufferSize)
+	var pending []byte
+	var written int64
+	for {
+		n, err := io.ReadFull(r, buffer)
+		if n > 0 {
+			if len(pending) > 0 {
+				count, writeErr := w.Write(pending)
+				written += int64(count)
+				if writeErr != nil {
+					return written, writeErr
+				}
+			}
+			_, _ = hash.Write(buffer[:n])
+			pending = append(pending[:0], buffer[:n]...)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
 		}
+		if err != nil {
+			return written, err
+		}
+	}
+	if !strings.EqualFold(hex.EncodeToString(hash.Sum(nil)), checksum) {
+		return written, errChecksumMismatch
+	}
+	count, err := w.Write(pending)
+	return written + int64(count), err
+}
+
+func (h *DownloadHandler) findRelease(plugin Plugin, version string) *Release {
+	if release, ok := plugin.FindRelease(version); ok {
+		return &release
 	}
 	return nil
 }
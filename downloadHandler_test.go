@@ -1,10 +1,14 @@
 package main
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 )
@@ -38,15 +42,27 @@ func TestUrlGeneratorWithoutForwardedHeader(t *testing.T) {
 		generator.DownloadUrl(Plugin{Name: "scm-download-plugin"}, "1.2.3"))
 }
 
+// contentChecksum is the sha256 checksum of the content returned by the download mock
+const contentChecksum = "ed7002b439e9ac845f22357d822bac1444730fbdb6016d3ec9432297b9ec9f73"
+
 func createMock(t *testing.T) func(url string) (resp *http.Response, err error) {
+	return createContentMock(t, http.StatusOK, "content")
+}
+
+func createContentMock(t *testing.T, status int, content string) func(url string) (resp *http.Response, err error) {
 	return func(url string) (resp *http.Response, err error) {
 		assert.Equal(t, "http://example.com", url)
-		return &http.Response{Body: ioutil.NopCloser(strings.NewReader("content"))}, nil
+		return &http.Response{
+			StatusCode:    status,
+			ContentLength: int64(len(content)),
+			Header:        http.Header{},
+			Body:          ioutil.NopCloser(strings.NewReader(content)),
+		}, nil
 	}
 }
 
 func TestDownloadHandler(t *testing.T) {
-	downloadHandler := DownloadHandler{plugins: createMap(testData), downloadPlugin: createMock(t)}
+	downloadHandler := DownloadHandler{catalog: testCatalog(), store: &httpArtifactStore{get: createMock(t)}}
 
 	rr := initRouter(t, "/api/v1/download/ssh-plugin/2.0", "trillian", downloadHandler.handle)
 
@@ -55,7 +71,7 @@ func TestDownloadHandler(t *testing.T) {
 }
 
 func TestDownloadHandlerPluginWithoutAuthentication(t *testing.T) {
-	downloadHandler := DownloadHandler{plugins: createMap(testData), downloadPlugin: createMock(t)}
+	downloadHandler := DownloadHandler{catalog: testCatalog(), store: &httpArtifactStore{get: createMock(t)}}
 
 	rr := initRouter(t, "/api/v1/download/ad-plugin/1.0", "", downloadHandler.handle)
 
@@ -64,15 +80,14 @@ func TestDownloadHandlerPluginWithoutAuthentication(t *testing.T) {
 }
 
 func TestDownloadHandlerCloudoguPluginWithoutSubject(t *testing.T) {
-	downloadHandler := DownloadHandler{plugins: createMap(testData), downloadPlugin: createMock(t)}
+	downloadHandler := DownloadHandler{catalog: testCatalog(), store: &httpArtifactStore{get: createMock(t)}}
 
 	rr := initRouter(t, "/api/v1/download/ssh-plugin/2.0", "", downloadHandler.handle)
 	assert.Equal(t, http.StatusUnauthorized, rr.Code)
 }
 
 func TestDownloadHandlerReleaseNotFound(t *testing.T) {
-	var plugins []Plugin
-	downloadHandler := DownloadHandler{plugins: createMap(plugins), downloadPlugin: nil}
+	downloadHandler := DownloadHandler{catalog: staticCatalog(NewCatalog(nil, nil)), store: nil}
 
 	rr := initRouter(t, "/api/v1/download/ssh-plugin/2.0", "trillian", downloadHandler.handle)
 	assert.Equal(t, http.StatusNotFound, rr.Code)
@@ -83,8 +98,221 @@ func TestDownloadHandlerRemoteFailes(t *testing.T) {
 		return nil, fmt.Errorf("failed to handle request: %s", url)
 	}
 
-	downloadHandler := DownloadHandler{plugins: createMap(testData), downloadPlugin: getMock}
+	downloadHandler := DownloadHandler{catalog: testCatalog(), store: &httpArtifactStore{get: getMock}}
 	rr := initRouter(t, "/api/v1/download/ssh-plugin/2.0", "dent", downloadHandler.handle)
 
 	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
 }
+
+func TestDownloadHandlerServesYankedReleases(t *testing.T) {
+	downloadHandler := DownloadHandler{catalog: staticCatalog(NewCatalog([]Plugin{yankedTestPlugin()}, nil)), store: &httpArtifactStore{get: createMock(t)}}
+
+	rr := initRouter(t, "/api/v1/download/scm-yanked-plugin/1.2.0", "", downloadHandler.handle)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "content", rr.Body.String())
+}
+
+func TestDownloadHandlerRejectsFailedUpstreamResponse(t *testing.T) {
+	downloadHandler := DownloadHandler{catalog: testCatalog(), store: &httpArtifactStore{get: createContentMock(t, http.StatusNotFound, "not found")}}
+
+	rr := initRouter(t, "/api/v1/download/ssh-plugin/2.0", "dent", downloadHandler.handle)
+
+	assert.Equal(t, http.StatusBadGateway, rr.Code)
+	assert.NotContains(t, rr.Body.String(), "not found")
+}
+
+func TestDownloadHandlerAbortsOnChecksumMismatch(t *testing.T) {
+	downloadHandler := DownloadHandler{catalog: testCatalog(), store: &httpArtifactStore{get: createContentMock(t, http.StatusOK, "tampered")}}
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		initRouter(t, "/api/v1/download/ssh-plugin/2.0", "dent", downloadHandler.handle)
+	})
+}
+
+func TestCopyVerifiedHoldsBackLastChunk(t *testing.T) {
+	content := strings.Repeat("a", downloadBufferSize+10)
+	var buffer bytes.Buffer
+
+	written, err := copyVerified(&buffer, strings.NewReader(content), "invalid")
+
+	assert.Equal(t, errChecksumMismatch, err)
+	assert.Equal(t, int64(downloadBufferSize), written)
+	assert.Equal(t, downloadBufferSize, buffer.Len())
+}
+
+func TestCopyVerifiedCopiesCompleteContent(t *testing.T) {
+	content := strings.Repeat("a", 3*downloadBufferSize)
+	checksum := sha256.Sum256([]byte(content))
+	var buffer bytes.Buffer
+
+	written, err := copyVerified(&buffer, strings.NewReader(content), hex.EncodeToString(checksum[:]))
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(content)), written)
+	assert.Equal(t, content, buffer.String())
+}
+
+func TestDownloadHandlerFillsCache(t *testing.T) {
+	cache, err := NewArtifactCache(t.TempDir(), 1024)
+	assert.NoError(t, err)
+	calls := 0
+	mock := createMock(t)
+	downloadHandler := DownloadHandler{catalog: testCatalog(), cache: cache, store: &httpArtifactStore{get: func(url string) (*http.Response, error) {
+		calls++
+		return mock(url)
+	}}}
+
+	for i := 0; i < 2; i++ {
+		rr := initRouter(t, "/api/v1/download/ssh-plugin/2.0", "trillian", downloadHandler.handle)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "content", rr.Body.String())
+	}
+	assert.Equal(t, 1, calls)
+}
+
+func TestDownloadHandlerServesArtifactsLargerThanTheCache(t *testing.T) {
+	cache, err := NewArtifactCache(t.TempDir(), 4)
+	assert.NoError(t, err)
+	calls := 0
+	mock := createMock(t)
+	downloadHandler := DownloadHandler{catalog: testCatalog(), cache: cache, store: &httpArtifactStore{get: func(url string) (*http.Response, error) {
+		calls++
+		return mock(url)
+	}}}
+
+	for i := 0; i < 2; i++ {
+		rr := initRouter(t, "/api/v1/download/ssh-plugin/2.0", "trillian", downloadHandler.handle)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "content", rr.Body.String())
+	}
+	assert.Equal(t, 2, calls)
+}
+
+func TestDownloadHandlerServesCachedArtifactIfUpstreamIsDown(t *testing.T) {
+	cache, err := NewArtifactCache(t.TempDir(), 1024)
+	assert.NoError(t, err)
+	fillCached(t, cache, contentChecksum, "content")
+	getMock := func(url string) (resp *http.Response, err error) {
+		return nil, fmt.Errorf("failed to handle request: %s", url)
+	}
+	downloadHandler := DownloadHandler{catalog: testCatalog(), cache: cache, store: &httpArtifactStore{get: getMock}}
+
+	rr := initRouter(t, "/api/v1/download/ssh-plugin/2.0", "trillian", downloadHandler.handle)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "content", rr.Body.String())
+}
+
+func TestDownloadHandlerDoesNotCacheArtifactWithWrongChecksum(t *testing.T) {
+	cache, err := NewArtifactCache(t.TempDir(), 1024)
+	assert.NoError(t, err)
+	downloadHandler := DownloadHandler{catalog: testCatalog(), cache: cache, store: &httpArtifactStore{get: createContentMock(t, http.StatusOK, "tampered")}}
+
+	rr := initRouter(t, "/api/v1/download/ssh-plugin/2.0", "trillian", downloadHandler.handle)
+
+	assert.Equal(t, http.StatusBadGateway, rr.Code)
+	_, ok := cache.Get(contentChecksum)
+	assert.False(t, ok)
+}
+
+func TestDownloadHandlerForwardsHeaders(t *testing.T) {
+	downloadHandler := DownloadHandler{catalog: testCatalog(), store: &httpArtifactStore{get: createMock(t)}}
+
+	rr := initRouter(t, "/api/v1/download/ssh-plugin/2.0", "trillian", downloadHandler.handle)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, `"`+contentChecksum+`"`, rr.Header().Get("ETag"))
+	assert.Equal(t, "7", rr.Header().Get("Content-Length"))
+	assert.Equal(t, "application/octet-stream", rr.Header().Get("Content-Type"))
+	assert.Equal(t, "bytes", rr.Header().Get("Accept-Ranges"))
+}
+
+func TestDownloadHandlerReturnsNotModifiedForMatchingETag(t *testing.T) {
+	downloadHandler := DownloadHandler{catalog: testCatalog(), store: nil}
+	header := http.Header{"If-None-Match": []string{`"other", "` + contentChecksum + `"`}}
+
+	rr := initRouterWithHeader(t, "/api/v1/download/ssh-plugin/2.0", "trillian", header, downloadHandler.handle)
+
+	assert.Equal(t, http.StatusNotModified, rr.Code)
+	assert.Empty(t, rr.Body.String())
+}
+
+func TestDownloadHandlerServesRange(t *testing.T) {
+	downloadHandler := DownloadHandler{catalog: testCatalog(), store: &httpArtifactStore{get: createMock(t)}}
+	header := http.Header{"Range": []string{"bytes=3-"}}
+
+	rr := initRouterWithHeader(t, "/api/v1/download/ssh-plugin/2.0", "trillian", header, downloadHandler.handle)
+
+	assert.Equal(t, http.StatusPartialContent, rr.Code)
+	assert.Equal(t, "bytes 3-6/7", rr.Header().Get("Content-Range"))
+	assert.Equal(t, "4", rr.Header().Get("Content-Length"))
+	assert.Equal(t, "tent", rr.Body.String())
+}
+
+func TestDownloadHandlerIgnoresRangeWithOutdatedIfRange(t *testing.T) {
+	downloadHandler := DownloadHandler{catalog: testCatalog(), store: &httpArtifactStore{get: createMock(t)}}
+	header := http.Header{"Range": []string{"bytes=3-"}, "If-Range": []string{`"outdated"`}}
+
+	rr := initRouterWithHeader(t, "/api/v1/download/ssh-plugin/2.0", "trillian", header, downloadHandler.handle)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "content", rr.Body.String())
+}
+
+func TestDownloadHandlerRejectsUnsatisfiableRange(t *testing.T) {
+	downloadHandler := DownloadHandler{catalog: testCatalog(), store: &httpArtifactStore{get: createMock(t)}}
+	header := http.Header{"Range": []string{"bytes=10-"}}
+
+	rr := initRouterWithHeader(t, "/api/v1/download/ssh-plugin/2.0", "trillian", header, downloadHandler.handle)
+
+	assert.Equal(t, http.StatusRequestedRangeNotSatisfiable, rr.Code)
+	assert.Equal(t, "bytes */7", rr.Header().Get("Content-Range"))
+}
+
+func TestDownloadHandlerServesRangeFromCache(t *testing.T) {
+	cache, err := NewArtifactCache(t.TempDir(), 1024)
+	assert.NoError(t, err)
+	fillCached(t, cache, contentChecksum, "content")
+	downloadHandler := DownloadHandler{catalog: testCatalog(), cache: cache, store: nil}
+	header := http.Header{"Range": []string{"bytes=0-2"}}
+
+	rr := initRouterWithHeader(t, "/api/v1/download/ssh-plugin/2.0", "trillian", header, downloadHandler.handle)
+
+	assert.Equal(t, http.StatusPartialContent, rr.Code)
+	assert.Equal(t, `"`+contentChecksum+`"`, rr.Header().Get("ETag"))
+	assert.Equal(t, "con", rr.Body.String())
+}
+
+func TestParseRange(t *testing.T) {
+	tests := map[string]*byteRange{
+		"bytes=0-99":     {start: 0, end: 99},
+		"bytes=100-":     {start: 100, end: 999},
+		"bytes=-100":     {start: 900, end: 999},
+		"bytes=900-2000": {start: 900, end: 999},
+		"bytes=0-1,5-6":  nil,
+		"items=0-1":      nil,
+	}
+	for header, expected := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Range", header)
+
+		byteRange, err := parseRange(r, "", 1000)
+
+		assert.NoError(t, err, header)
+		assert.Equal(t, expected, byteRange, header)
+	}
+}
+
+func TestRangeWriterWritesOnlyBytesOfRange(t *testing.T) {
+	var buffer bytes.Buffer
+	writer := &rangeWriter{writer: &buffer, byteRange: byteRange{start: 3, end: 8}}
+
+	for _, chunk := range []string{"abcd", "efgh", "ijkl"} {
+		n, err := writer.Write([]byte(chunk))
+		assert.NoError(t, err)
+		assert.Equal(t, 4, n)
+	}
+
+	assert.Equal(t, "defghi", buffer.String())
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"github.com/pkg/errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var errRangeNotSatisfiable = errors.New("range not satisfiable")
+
+// byteRange is an inclusive range of bytes
+type byteRange struct {
+	start int64
+	end   int64
+}
+
+func (b byteRange) length() int64 {
+	return b.end - b.start + 1
+}
+
+// createETag uses the checksum of the release as strong etag
+func createETag(release *Release) string {
+	if release.Checksum == "" {
+		return ""
+	}
+	return `"` + strings.ToLower(release.Checksum) + `"`
+}
+
+// etagMatches compares the etag with the value of an If-None-Match header using the weak comparison.
+func etagMatches(header string, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRange returns the requested range of the content with the given size. Only single ranges are supported,
+// for other requests the complete content is served. The range is ignored as well, if the If-Range header does not
+// match the etag.
+func parseRange(r *http.Request, etag string, size int64) (*byteRange, error) {
+	header := r.Header.Get("Range")
+	if header == "" || !strings.HasPrefix(header, "bytes=") || strings.Contains(header, ",") {
+		return nil, nil
+	}
+	if ifRange := r.Header.Get("If-Range"); ifRange != "" && (etag == "" || ifRange != etag) {
+		return nil, nil
+	}
+
+	spec := strings.SplitN(strings.TrimSpace(strings.TrimPrefix(header, "bytes=")), "-", 2)
+	if len(spec) != 2 {
+		return nil, nil
+	}
+	if spec[0] == "" {
+		// suffix range, e.g. the last 500 bytes
+		suffix, err := strconv.ParseInt(spec[1], 10, 64)
+		if err != nil || suffix <= 0 {
+			return nil, errRangeNotSatisfiable
+		}
+		if suffix > size {
+			suffix = size
+		}
+		return &byteRange{start: size - suffix, end: size - 1}, nil
+	}
+
+	start, err := strconv.ParseInt(spec[0], 10, 64)
+	if err != nil || start < 0 || start >= size {
+		return nil, errRangeNotSatisfiable
+	}
+	end := size - 1
+	if spec[1] != "" {
+		end, err = strconv.ParseInt(spec[1], 10, 64)
+		if err != nil || end < start {
+			return nil, errRangeNotSatisfiable
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+	return &byteRange{start: start, end: end}, nil
+}
+
+// rangeWriter writes only the bytes of the range to the underlying writer and discards all others.
+type rangeWriter struct {
+	writer    io.Writer
+	byteRange byteRange
+	offset    int64
+}
+
+func (w *rangeWriter) Write(data []byte) (int, error) {
+	length := int64(len(data))
+	start := w.byteRange.start - w.offset
+	end := w.byteRange.end - w.offset + 1
+	w.offset += length
+
+	if start < 0 {
+		start = 0
+	}
+	if end > length {
+		end = length
+	}
+	if start < end {
+		if _, err := w.writer.Write(data[start:end]); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/pkg/errors"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	// DownloadStrategyProxy streams the artifact through the plugin center
+	DownloadStrategyProxy = "proxy"
+	// DownloadStrategyRedirect redirects the client to the url of the release
+	DownloadStrategyRedirect = "redirect"
+	// DownloadStrategySigned redirects the client to a short-lived signed url of the release
+	DownloadStrategySigned = "signed"
+)
+
+// DownloadStrategies maps plugin types to the strategy which is used to serve their downloads.
+type DownloadStrategies struct {
+	types  map[string]string
+	signer *urlSigner
+}
+
+func NewDownloadStrategies(configuration Configuration) (DownloadStrategies, error) {
+	strategies := DownloadStrategies{types: make(map[string]string)}
+	for pluginType, strategy := range configuration.DownloadStrategies {
+		switch strategy {
+		case DownloadStrategyProxy:
+		case DownloadStrategyRedirect:
+			// the url of the release would be public for everyone who knows it
+			if (Plugin{Type: pluginType}).RequiresAuthentication() {
+				return strategies, errors.Errorf("plugins of type %s require authentication and could not be downloaded with strategy %s", pluginType, strategy)
+			}
+		case DownloadStrategySigned:
+			if configuration.DownloadSigningKey == "" {
+				return strategies, errors.Errorf("strategy %s for plugins of type %s requires a download signing key", strategy, pluginType)
+			}
+			strategies.signer = &urlSigner{key: []byte(configuration.DownloadSigningKey), ttl: configuration.DownloadSignedUrlTtl}
+		default:
+			return strategies, errors.Errorf("unknown download strategy %s for plugins of type %s", strategy, pluginType)
+		}
+		strategies.types[pluginType] = strategy
+	}
+	return strategies, nil
+}
+
+// For returns the strategy for the plugin, proxy is used if nothing else is configured.
+func (d DownloadStrategies) For(plugin Plugin) string {
+	if strategy, ok := d.types[plugin.GetType()]; ok {
+		return strategy
+	}
+	return DownloadStrategyProxy
+}
+
+// urlSigner appends an expires timestamp and a hmac signature to urls. The signature is the hex encoded
+// hmac-sha256 of the url with all query parameters except the signature, sorted by key.
+type urlSigner struct {
+	key []byte
+	ttl time.Duration
+}
+
+func (s *urlSigner) Sign(rawUrl string) (string, error) {
+	signedUrl, err := url.Parse(rawUrl)
+	if err != nil {
+		return "", errors.Wrapf(err, "could not parse url %s", rawUrl)
+	}
+	query := signedUrl.Query()
+	query.Del("signature")
+	query.Set("expires", strconv.FormatInt(now().Add(s.ttl).Unix(), 10))
+	signedUrl.RawQuery = query.Encode()
+
+	query.Set("signature", s.signature(signedUrl.String()))
+	signedUrl.RawQuery = query.Encode()
+	return signedUrl.String(), nil
+}
+
+func (s *urlSigner) signature(value string) string {
+	mac := hmac.New(sha256.New, s.key)
+	_, _ = mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
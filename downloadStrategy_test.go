@@ -0,0 +1,90 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestDownloadStrategiesDefaultsToProxy(t *testing.T) {
+	strategies, err := NewDownloadStrategies(Configuration{})
+	assert.NoError(t, err)
+
+	assert.Equal(t, DownloadStrategyProxy, strategies.For(Plugin{}))
+	assert.Equal(t, DownloadStrategyProxy, strategies.For(Plugin{Type: "CLOUDOGU"}))
+}
+
+func TestDownloadStrategiesFailsForUnknownStrategy(t *testing.T) {
+	_, err := NewDownloadStrategies(Configuration{DownloadStrategies: map[string]string{"SCM": "teleport"}})
+	assert.Error(t, err)
+}
+
+func TestDownloadStrategiesFailsForRedirectOfAuthenticatedPlugins(t *testing.T) {
+	_, err := NewDownloadStrategies(Configuration{DownloadStrategies: map[string]string{"CLOUDOGU": DownloadStrategyRedirect}})
+	assert.Error(t, err)
+}
+
+func TestDownloadStrategiesFailsForSignedWithoutKey(t *testing.T) {
+	_, err := NewDownloadStrategies(Configuration{DownloadStrategies: map[string]string{"SCM": DownloadStrategySigned}})
+	assert.Error(t, err)
+}
+
+func TestUrlSignerSignsUrl(t *testing.T) {
+	now = func() time.Time { return time.Unix(1600000000, 0) }
+	defer func() { now = time.Now }()
+	signer := urlSigner{key: []byte("secret"), ttl: 5 * time.Minute}
+
+	signedUrl, err := signer.Sign("https://download.scm-manager.org/plugins/a.smp?b=1")
+	assert.NoError(t, err)
+
+	parsed, err := url.Parse(signedUrl)
+	assert.NoError(t, err)
+	query := parsed.Query()
+	assert.Equal(t, "1600000300", query.Get("expires"))
+	signature := query.Get("signature")
+	query.Del("signature")
+	parsed.RawQuery = query.Encode()
+	assert.Equal(t, signer.signature(parsed.String()), signature)
+}
+
+func TestDownloadHandlerRedirectsToReleaseUrl(t *testing.T) {
+	strategies, err := NewDownloadStrategies(Configuration{DownloadStrategies: map[string]string{"SCM": DownloadStrategyRedirect}})
+	assert.NoError(t, err)
+	downloadHandler := DownloadHandler{catalog: testCatalog(), strategies: strategies, store: nil}
+
+	rr := initRouter(t, "/api/v1/download/ad-plugin/1.0", "", downloadHandler.handle)
+
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Equal(t, "http://example.com", rr.Header().Get("Location"))
+}
+
+func TestDownloadHandlerRedirectsToSignedUrl(t *testing.T) {
+	strategies, err := NewDownloadStrategies(Configuration{
+		DownloadStrategies:   map[string]string{"CLOUDOGU": DownloadStrategySigned},
+		DownloadSigningKey:   "secret",
+		DownloadSignedUrlTtl: time.Minute,
+	})
+	assert.NoError(t, err)
+	downloadHandler := DownloadHandler{catalog: testCatalog(), strategies: strategies, store: nil}
+
+	rr := initRouter(t, "/api/v1/download/ssh-plugin/2.0", "trillian", downloadHandler.handle)
+
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Contains(t, rr.Header().Get("Location"), "http://example.com?expires=")
+	assert.Contains(t, rr.Header().Get("Location"), "&signature=")
+}
+
+func TestDownloadHandlerRequiresAuthenticationForSignedUrls(t *testing.T) {
+	strategies, err := NewDownloadStrategies(Configuration{
+		DownloadStrategies: map[string]string{"CLOUDOGU": DownloadStrategySigned},
+		DownloadSigningKey: "secret",
+	})
+	assert.NoError(t, err)
+	downloadHandler := DownloadHandler{catalog: testCatalog(), strategies: strategies, store: nil}
+
+	rr := initRouter(t, "/api/v1/download/ssh-plugin/2.0", "", downloadHandler.handle)
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+}
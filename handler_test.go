@@ -9,10 +9,15 @@ import (
 )
 
 func initRouter(t *testing.T, url string, subject string, handler func(http.ResponseWriter, *http.Request)) *httptest.ResponseRecorder {
+	return initRouterWithHeader(t, url, subject, http.Header{}, handler)
+}
+
+func initRouterWithHeader(t *testing.T, url string, subject string, header http.Header, handler func(http.ResponseWriter, *http.Request)) *httptest.ResponseRecorder {
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
+	req.Header = header
 
 	if subject != "" {
 		ctx := context.WithValue(req.Context(), "subject", &Subject{Id: subject})
@@ -23,12 +28,28 @@ func initRouter(t *testing.T, url string, subject string, handler func(http.Resp
 
 	router := mux.NewRouter()
 	router.HandleFunc("/api/v1/plugins/{version}", handler)
+	router.HandleFunc("/api/v1/plugins/{version}/{plugin}/releases", handler)
+	router.HandleFunc("/api/v1/plugin/{name}", handler)
 	router.HandleFunc("/api/v1/download/{plugin}/{version}", handler)
+	router.HandleFunc("/api/v1/resolve/{version}", handler)
+	router.HandleFunc("/api/v1/plugin-sets/{version}", handler)
+	router.HandleFunc("/api/v1/plugin-sets/{id}/images/{name}", handler)
 	router.ServeHTTP(rr, req)
 
 	return rr
 }
 
+func testCatalog() *CatalogHolder {
+	return staticCatalog(NewCatalog(testData, testDataPluginSets))
+}
+
+func staticCatalog(catalog *Catalog) *CatalogHolder {
+	holder, _ := NewCatalogHolder(func() (*Catalog, error) {
+		return catalog, nil
+	})
+	return holder
+}
+
 var testData = []Plugin{
 	{
 		Name:        "ssh-plugin",
@@ -45,11 +66,11 @@ var testData = []Plugin{
 					Arch:       "64",
 					MinVersion: "2.0.1",
 				},
-				Dependencies:         []string{"scm-mail-plugin"},
-				OptionalDependencies: []string{"scm-review-plugin"},
+				Dependencies:         Dependencies{{Name: "scm-mail-plugin"}},
+				OptionalDependencies: Dependencies{{Name: "scm-review-plugin"}},
 				Url:                  "http://example.com",
 				Date:                 "1.01.2019",
-				Checksum:             "abc",
+				Checksum:             contentChecksum,
 				InstallLink:          "myCloudogu.com/install/my_plugin",
 			},
 			{
@@ -61,7 +82,7 @@ var testData = []Plugin{
 				},
 				Url:      "http://example.com",
 				Date:     "1.01.2019",
-				Checksum: "abc",
+				Checksum: contentChecksum,
 			},
 			{
 				Version: "0.1",
@@ -70,7 +91,7 @@ var testData = []Plugin{
 				},
 				Url:      "http://example.com",
 				Date:     "1.01.2019",
-				Checksum: "abc",
+				Checksum: contentChecksum,
 			},
 		},
 		Author: "Cloudogu",
@@ -88,7 +109,7 @@ var testData = []Plugin{
 				},
 				Url:      "http://example.com",
 				Date:     "1.01.2019",
-				Checksum: "abc",
+				Checksum: contentChecksum,
 			},
 		},
 		Author: "Microsoft",
@@ -111,6 +132,9 @@ var testDataPluginSets = []PluginSet{
 				Features: []string{"Merkmal 1", "Merkmal 2", "Merkmal 3"},
 			},
 		},
+		Images: map[string]Image{
+			"check": {ContentType: "image/svg+xml", ETag: `"check"`, Data: []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`)},
+		},
 	},
 	{
 		Id:       "administration-and-management",
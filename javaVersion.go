@@ -0,0 +1,26 @@
+package main
+
+import (
+	"github.com/hashicorp/go-version"
+	"strings"
+)
+
+// parseJavaVersion parses java versions in the legacy format (1.8.0_292) as well as in the current one (11.0.2+9).
+// Legacy versions are normalized, so that 1.8.0_292 becomes 8.0.292.
+func parseJavaVersion(value string) (*version.Version, error) {
+	return version.NewVersion(normalizeJavaVersion(value))
+}
+
+func normalizeJavaVersion(value string) string {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "1.") {
+		value = value[2:]
+	}
+	return strings.Replace(value, "_", ".", 1)
+}
+
+// javaVersionAtMost checks whether the given java version is not higher than the max value,
+// see versionAtMost for the comparison.
+func javaVersionAtMost(javaVersion *version.Version, maxValue string) (bool, error) {
+	return versionAtMost(javaVersion, normalizeJavaVersion(maxValue))
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseJavaVersion(t *testing.T) {
+	tests := map[string]string{
+		"1.8.0_292": "8.0.292",
+		"1.8":       "8.0.0",
+		"11.0.2":    "11.0.2",
+		"11.0.2+9":  "11.0.2+9",
+		"17":        "17.0.0",
+		"17-ea":     "17.0.0-ea",
+	}
+	for value, expected := range tests {
+		v, err := parseJavaVersion(value)
+		assert.NoError(t, err, value)
+		assert.Equal(t, expected, v.String(), value)
+	}
+}
+
+func TestParseJavaVersionFailsForInvalidVersions(t *testing.T) {
+	_, err := parseJavaVersion("java")
+	assert.Error(t, err)
+}
+
+func TestJavaVersionAtMost(t *testing.T) {
+	javaVersion, err := parseJavaVersion("1.8.0_292")
+	assert.NoError(t, err)
+
+	for maxValue, expected := range map[string]bool{"1.8": true, "8": true, "8.0.291": false, "11": true, "1.7": false} {
+		ok, err := javaVersionAtMost(javaVersion, maxValue)
+		assert.NoError(t, err, maxValue)
+		assert.Equal(t, expected, ok, maxValue)
+	}
+}
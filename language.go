@@ -0,0 +1,149 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const defaultLanguage = "en"
+
+// normalizeLanguage converts a language code like pt_BR or zh-Hant to the lower case form with dashes,
+// which is used to compare language codes.
+func normalizeLanguage(language string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(language), "_", "-"))
+}
+
+func baseLanguage(language string) string {
+	if i := strings.Index(language, "-"); i > 0 {
+		return language[:i]
+	}
+	return language
+}
+
+// defaultScripts are the scripts of languages with a region, which are written in different scripts
+// depending on the region
+var defaultScripts = map[string]string{
+	"zh-tw": "hant",
+	"zh-hk": "hant",
+	"zh-mo": "hant",
+	"zh-cn": "hans",
+	"zh-sg": "hans",
+}
+
+// languageScript returns the script subtag of the normalized language (e.g. hant of zh-hant-tw), the default
+// script of its region (e.g. hant of zh-tw) or an empty string.
+func languageScript(language string) string {
+	subtags := strings.Split(language, "-")
+	if len(subtags) > 1 && len(subtags[1]) == 4 && isLetters(subtags[1]) {
+		return subtags[1]
+	}
+	if len(subtags) > 1 {
+		return defaultScripts[subtags[0]+"-"+subtags[1]]
+	}
+	return ""
+}
+
+func isLetters(value string) bool {
+	for _, r := range value {
+		if r < 'a' || r > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+// parseAcceptLanguage returns the normalized languages of an Accept-Language header ordered by their quality.
+func parseAcceptLanguage(header string) []string {
+	type weightedLanguage struct {
+		language string
+		quality  float64
+	}
+	var weighted []weightedLanguage
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		language := normalizeLanguage(fields[0])
+		if language == "" || language == "*" {
+			continue
+		}
+		quality := 1.0
+		for _, parameter := range fields[1:] {
+			parameter = strings.TrimSpace(parameter)
+			if strings.HasPrefix(parameter, "q=") {
+				if q, err := strconv.ParseFloat(parameter[2:], 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		if quality > 0 {
+			weighted = append(weighted, weightedLanguage{language, quality})
+		}
+	}
+	sort.SliceStable(weighted, func(i, j int) bool { return weighted[i].quality > weighted[j].quality })
+
+	languages := []string{}
+	for _, w := range weighted {
+		languages = append(languages, w.language)
+	}
+	return languages
+}
+
+// negotiateLanguage returns the available language which matches the requested languages best.
+// A requested language matches an available language with the same code or, if there is none,
+// the same base language (e.g. de-DE matches de). Languages with different scripts do not match (e.g. zh-Hans
+// does not match zh-Hant or zh-TW), but a language without script matches every script. If nothing matches, english is used and if english
+// is not available the first available language in alphabetical order.
+func negotiateLanguage(requested []string, available []string) string {
+	if len(available) == 0 {
+		return ""
+	}
+	if match, ok := matchLanguage(requested, available); ok {
+		return match
+	}
+	if match, ok := findLanguage(available, defaultLanguage); ok {
+		return match
+	}
+	sorted := append([]string{}, available...)
+	sort.Strings(sorted)
+	return sorted[0]
+}
+
+// matchLanguage returns the available language which matches the requested languages best, without any fallback.
+func matchLanguage(requested []string, available []string) (string, bool) {
+	// sorted to get the same match for every request, if multiple languages share the base language
+	available = append([]string{}, available...)
+	sort.Strings(available)
+	for _, language := range requested {
+		if match, ok := findLanguage(available, language); ok {
+			return match, true
+		}
+	}
+	return "", false
+}
+
+func findLanguage(available []string, language string) (string, bool) {
+	for _, candidate := range available {
+		if normalizeLanguage(candidate) == language {
+			return candidate, true
+		}
+	}
+	for _, candidate := range available {
+		normalized := normalizeLanguage(candidate)
+		if baseLanguage(normalized) == baseLanguage(language) && scriptsMatch(languageScript(normalized), languageScript(language)) {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+func scriptsMatch(script string, other string) bool {
+	return script == "" || other == "" || script == other
+}
+
+// requestedLanguages returns the languages of the lang parameter or the Accept-Language header of the request.
+func requestedLanguages(lang string, acceptLanguage string) []string {
+	if lang != "" {
+		return []string{normalizeLanguage(lang)}
+	}
+	return parseAcceptLanguage(acceptLanguage)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseAcceptLanguageOrdersByQuality(t *testing.T) {
+	languages := parseAcceptLanguage("fr;q=0.5, de-DE, en;q=0.8, *;q=0.1, it;q=0")
+
+	assert.Equal(t, []string{"de-de", "en", "fr"}, languages)
+}
+
+func TestParseAcceptLanguageWithEmptyHeader(t *testing.T) {
+	assert.Empty(t, parseAcceptLanguage(""))
+}
+
+func TestNegotiateLanguage(t *testing.T) {
+	available := []string{"en", "de", "pt_BR", "zh-Hant"}
+
+	assert.Equal(t, "de", negotiateLanguage([]string{"de"}, available))
+	assert.Equal(t, "de", negotiateLanguage([]string{"de-at"}, available))
+	assert.Equal(t, "pt_BR", negotiateLanguage([]string{"pt-br"}, available))
+	assert.Equal(t, "pt_BR", negotiateLanguage([]string{"pt"}, available))
+	assert.Equal(t, "zh-Hant", negotiateLanguage([]string{"zh-hant"}, available))
+	assert.Equal(t, "de", negotiateLanguage([]string{"fr", "de"}, available))
+	assert.Equal(t, "en", negotiateLanguage([]string{"fr"}, available))
+	assert.Equal(t, "en", negotiateLanguage(nil, available))
+}
+
+func TestNegotiateLanguageComparesScripts(t *testing.T) {
+	available := []string{"en", "zh-Hant"}
+
+	assert.Equal(t, "en", negotiateLanguage([]string{"zh-hans"}, available))
+	assert.Equal(t, "en", negotiateLanguage([]string{"zh-hans-cn"}, available))
+	assert.Equal(t, "zh-Hant", negotiateLanguage([]string{"zh-hant-tw"}, available))
+	assert.Equal(t, "zh-Hant", negotiateLanguage([]string{"zh"}, available))
+	assert.Equal(t, "zh-Hans", negotiateLanguage([]string{"zh-hans"}, []string{"zh-Hant", "zh-Hans"}))
+}
+
+func TestNegotiateLanguageUsesDefaultScriptOfRegion(t *testing.T) {
+	available := []string{"en", "zh-Hans", "zh-Hant"}
+
+	assert.Equal(t, "zh-Hant", negotiateLanguage([]string{"zh-tw"}, available))
+	assert.Equal(t, "zh-Hant", negotiateLanguage([]string{"zh-hk"}, available))
+	assert.Equal(t, "zh-Hans", negotiateLanguage([]string{"zh-cn"}, available))
+	assert.Equal(t, "en", negotiateLanguage([]string{"zh-cn"}, []string{"en", "zh-Hant"}))
+	assert.Equal(t, "zh_TW", negotiateLanguage([]string{"zh-hant"}, []string{"zh_CN", "zh_TW"}))
+}
+
+func TestNegotiateLanguageWithoutEnglish(t *testing.T) {
+	assert.Equal(t, "de", negotiateLanguage([]string{"fr"}, []string{"es", "de"}))
+	assert.Equal(t, "", negotiateLanguage([]string{"fr"}, nil))
+}
+
+func TestRequestedLanguagesPrefersParameter(t *testing.T) {
+	assert.Equal(t, []string{"pt-br"}, requestedLanguages("pt_BR", "de"))
+	assert.Equal(t, []string{"de"}, requestedLanguages("", "de"))
+}
@@ -0,0 +1,373 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/blang/semver/v4"
+	"github.com/hashicorp/go-version"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func runMirror(args []string, out io.Writer) int {
+	flags := flag.NewFlagSet("mirror", flag.ContinueOnError)
+	flags.SetOutput(out)
+	source := flags.String("source", "", "url of the plugins endpoint of a plugin center (e.g. https://plugin-center-api.scm-manager.org/api/v1/plugins/2.0.0) or a directory with plugin descriptors")
+	target := flags.String("target", "", "directory in which the plugin descriptors and artifacts are written")
+	urlPrefix := flags.String("url-prefix", "", "prefix for the urls of the mirrored artifacts (default file url of the target directory)")
+	token := flags.String("token", "", "token which is used to download plugins which require authentication")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *source == "" || *target == "" {
+		_, _ = fmt.Fprintln(out, "source and target are required")
+		flags.PrintDefaults()
+		return 2
+	}
+
+	mirror, err := newMirror(*target, *urlPrefix, *token)
+	if err != nil {
+		_, _ = fmt.Fprintln(out, err)
+		return 1
+	}
+	plugins, problems, err := readMirrorSource(*source, mirror.get)
+	if err != nil {
+		_, _ = fmt.Fprintln(out, err)
+		return 1
+	}
+	// broken descriptors are skipped by the scanner, the mirror contains only the valid ones
+	for _, problem := range problems {
+		_, _ = fmt.Fprintln(out, "skipping problem in source:", problem)
+	}
+
+	result := mirror.mirrorPlugins(plugins)
+	for _, message := range result.Failures {
+		_, _ = fmt.Fprintln(out, message)
+	}
+	_, _ = fmt.Fprintf(out, "mirrored %d releases, %d were up to date, %d failed\n", result.Downloaded, result.UpToDate, len(result.Failures))
+	if len(result.Failures) > 0 {
+		return 1
+	}
+	return 0
+}
+
+type MirrorResult struct {
+	Downloaded int
+	UpToDate   int
+	Failures   []string
+}
+
+type mirror struct {
+	target    string
+	urlPrefix string
+	store     ArtifactStore
+	get       func(url string) (*http.Response, error)
+}
+
+func newMirror(target string, urlPrefix string, token string) (*mirror, error) {
+	absoluteTarget, err := filepath.Abs(target)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not resolve target directory %s", target)
+	}
+	if urlPrefix == "" {
+		urlPrefix = "file://" + filepath.ToSlash(absoluteTarget)
+	}
+
+	client := newArtifactHttpClient(artifactReadTimeout)
+	get := client.Get
+	if token != "" {
+		get = func(url string) (*http.Response, error) {
+			req, err := http.NewRequest(http.MethodGet, url, nil)
+			if err != nil {
+				return nil, err
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+			return client.Do(req)
+		}
+	}
+
+	return &mirror{
+		target:    absoluteTarget,
+		urlPrefix: strings.TrimSuffix(urlPrefix, "/"),
+		store: schemeArtifactStore{
+			http: &httpArtifactStore{get: get},
+			// the mirror is started by the operator of the source, so local artifacts may be stored anywhere
+			file: fileArtifactStore{root: string(filepath.Separator)},
+		},
+		get: get,
+	}, nil
+}
+
+// readMirrorSource reads the plugins from a descriptor directory or from a plugin center. The plugins endpoint of a
+// plugin center is only used to find the plugins, all releases and their metadata are read from the detail endpoint
+// of each plugin.
+func readMirrorSource(source string, get func(url string) (*http.Response, error)) ([]Plugin, Problems, error) {
+	if !isHttpUrl(source) {
+		return scanDirectory(source)
+	}
+
+	var response struct {
+		Embedded struct {
+			Plugins []PluginResult `json:"plugins"`
+		} `json:"_embedded"`
+	}
+	if err := getJson(get, source, &response); err != nil {
+		return nil, nil, err
+	}
+
+	var plugins []Plugin
+	var problems Problems
+	for _, result := range response.Embedded.Plugins {
+		if !isValidPluginName(result.Name) {
+			problems.Add(source, 0, "name", fmt.Sprintf("%s is not a valid plugin name", result.Name))
+			continue
+		}
+		detailUrl, err := pluginDetailUrl(source, result.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+		var detail PluginDetail
+		if err := getJson(get, detailUrl, &detail); err != nil {
+			return nil, nil, err
+		}
+		plugin, err := pluginFromDetail(detail)
+		if err != nil {
+			return nil, nil, err
+		}
+		plugins = append(plugins, plugin)
+	}
+	return plugins, problems, nil
+}
+
+func getJson(get func(url string) (*http.Response, error), location string, value interface{}) error {
+	resp, err := get(location)
+	if err != nil {
+		return errors.Wrapf(err, "could not read %s", location)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("could not read %s, got status %d", location, resp.StatusCode)
+	}
+	return errors.Wrapf(json.NewDecoder(resp.Body).Decode(value), "could not parse %s", location)
+}
+
+// pluginDetailUrl returns the url of the detail endpoint of the plugin on the plugin center of the plugins endpoint.
+func pluginDetailUrl(source string, name string) (string, error) {
+	sourceUrl, err := url.Parse(source)
+	if err != nil {
+		return "", errors.Wrapf(err, "could not parse %s", source)
+	}
+	index := strings.Index(sourceUrl.Path, "/api/v1/plugins/")
+	if index < 0 {
+		return "", errors.Errorf("%s is not the plugins endpoint of a plugin center", source)
+	}
+	detailUrl := url.URL{Scheme: sourceUrl.Scheme, User: sourceUrl.User, Host: sourceUrl.Host}
+	detailUrl.Path = sourceUrl.Path[:index] + "/api/v1/plugin/" + name
+	return detailUrl.String(), nil
+}
+
+// pluginFromDetail restores the descriptor of a plugin with all of its releases from the api representation.
+func pluginFromDetail(detail PluginDetail) (Plugin, error) {
+	plugin := Plugin{
+		Name:          detail.Name,
+		DisplayName:   detail.DisplayName,
+		Description:   detail.Description,
+		Category:      detail.Category,
+		CategoryLabel: detail.CategoryLabel,
+		Author:        detail.Author,
+		Type:          detail.Type,
+		AvatarUrl:     detail.AvatarUrl,
+		Translations:  detail.Translations,
+	}
+	for _, releaseDetail := range detail.Releases {
+		release, err := releaseFromDetail(detail.Name, releaseDetail)
+		if err != nil {
+			return Plugin{}, errors.Wrapf(err, "could not parse release %s of %s", releaseDetail.Version, detail.Name)
+		}
+		plugin.Releases = append(plugin.Releases, release)
+	}
+	return plugin, nil
+}
+
+func releaseFromDetail(pluginName string, detail ReleaseDetail) (Release, error) {
+	var conditions Conditions
+	data, err := json.Marshal(detail.Conditions)
+	if err != nil {
+		return Release{}, errors.Wrap(err, "could not marshal conditions")
+	}
+	err = json.Unmarshal(data, &conditions)
+	if err != nil {
+		return Release{}, errors.Wrap(err, "could not parse conditions")
+	}
+
+	dependencies, err := dependenciesFromResult(detail.Dependencies, detail.DependencyVersions)
+	if err != nil {
+		return Release{}, errors.Wrap(err, "could not parse dependencies")
+	}
+	optionalDependencies, err := dependenciesFromResult(detail.OptionalDependencies, detail.OptionalDependencyVersions)
+	if err != nil {
+		return Release{}, errors.Wrap(err, "could not parse optional dependencies")
+	}
+
+	return Release{
+		Plugin:               pluginName,
+		Version:              detail.Version,
+		Conditions:           conditions,
+		Dependencies:         dependencies,
+		OptionalDependencies: optionalDependencies,
+		Url:                  detail.Links["download"].Href,
+		Date:                 detail.Date,
+		Checksum:             detail.Checksum,
+		InstallLink:          detail.Links["install"].Href,
+		Channel:              detail.Channel,
+		Rollout:              detail.Rollout,
+		Yanked:               detail.Yanked,
+		Advisories:           detail.Advisories,
+	}, nil
+}
+
+func dependenciesFromResult(names []string, versions map[string]string) (Dependencies, error) {
+	var dependencies Dependencies
+	for _, name := range names {
+		dependency := Dependency{Name: name}
+		if value, ok := versions[name]; ok {
+			versionRange, err := semver.ParseRange(value)
+			if err != nil {
+				return nil, errors.Wrapf(err, "could not parse version range %s of %s", value, name)
+			}
+			dependency.Versions = VersionRange{Value: value, Range: versionRange}
+		}
+		dependencies = append(dependencies, dependency)
+	}
+	return dependencies, nil
+}
+
+func (m *mirror) mirrorPlugins(plugins []Plugin) MirrorResult {
+	var result MirrorResult
+	for _, plugin := range plugins {
+		err := m.writePluginYml(plugin)
+		if err != nil {
+			result.Failures = append(result.Failures, err.Error())
+			continue
+		}
+		for _, release := range plugin.Releases {
+			downloaded, err := m.mirrorRelease(plugin, release)
+			if err != nil {
+				result.Failures = append(result.Failures, fmt.Sprintf("%s %s: %v", plugin.Name, release.Version, err))
+			} else if downloaded {
+				result.Downloaded++
+			} else {
+				result.UpToDate++
+			}
+		}
+	}
+	return result
+}
+
+// writePluginYml writes the plugin descriptor. The name of the plugin is checked, because it is used as directory
+// name and may come from a remote plugin center.
+func (m *mirror) writePluginYml(plugin Plugin) error {
+	if !isValidPluginName(plugin.Name) {
+		return errors.Errorf("%s is not a valid plugin name", plugin.Name)
+	}
+	path, err := joinWithin(m.target, plugin.Name, "plugin.yml")
+	if err != nil {
+		return err
+	}
+	plugin.Releases = nil
+	return writeYml(path, plugin)
+}
+
+// mirrorRelease downloads the artifact of the release, if it is not already mirrored, and writes the release
+// descriptor with the url of the mirrored artifact.
+func (m *mirror) mirrorRelease(plugin Plugin, release Release) (bool, error) {
+	if release.Url == "" {
+		return false, errors.New("release has no download url, maybe the plugin requires authentication")
+	}
+	if !isSha256Checksum(release.Checksum) {
+		return false, errors.Errorf("release has no valid sha256 checksum")
+	}
+	// the version is used in file names, so it has to be checked like the scanner does
+	if _, err := version.NewVersion(release.Version); err != nil {
+		return false, errors.Errorf("%s is not a valid version", release.Version)
+	}
+
+	artifactName := plugin.Name + "-" + release.Version + ".smp"
+	artifactPath, err := joinWithin(m.target, plugin.Name, "artifacts", artifactName)
+	if err != nil {
+		return false, err
+	}
+	releasePath, err := joinWithin(m.target, plugin.Name, "releases", strings.ReplaceAll(release.Version, ".", "-")+".yml")
+	if err != nil {
+		return false, err
+	}
+
+	downloaded := false
+	if !hasChecksum(artifactPath, release.Checksum) {
+		err := m.download(release, artifactPath)
+		if err != nil {
+			return false, err
+		}
+		downloaded = true
+	}
+
+	release.Plugin = plugin.Name
+	release.Url = m.urlPrefix + "/" + plugin.Name + "/artifacts/" + artifactName
+	return downloaded, writeYml(releasePath, release)
+}
+
+func (m *mirror) download(release Release, path string) error {
+	artifact, err := m.store.Open(release.Url)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = artifact.Body.Close()
+	}()
+
+	_, err = writeArtifact(path, artifact.Body, release.Checksum)
+	if err == errChecksumMismatch {
+		return errors.Errorf("checksum of %s does not match %s", release.Url, release.Checksum)
+	}
+	return err
+}
+
+// hasChecksum returns true, if the file exists and matches the checksum.
+func hasChecksum(path string, checksum string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return false
+	}
+	return strings.EqualFold(hex.EncodeToString(hash.Sum(nil)), checksum)
+}
+
+func writeYml(path string, value interface{}) error {
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		return errors.Wrapf(err, "could not marshal %s", path)
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return errors.Wrapf(err, "could not create directory for %s", path)
+	}
+	return errors.Wrapf(ioutil.WriteFile(path, data, 0644), "could not write %s", path)
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func createMirrorSource(t *testing.T, content string) string {
+	source := t.TempDir()
+	artifact := filepath.Join(t.TempDir(), "scm-mail-plugin.smp")
+	assert.NoError(t, ioutil.WriteFile(artifact, []byte(content), 0644))
+
+	plugin := Plugin{Name: "scm-mail-plugin", DisplayName: "Mail", Category: "administration"}
+	assert.NoError(t, writeYml(filepath.Join(source, "scm-mail-plugin", "plugin.yml"), plugin))
+	release := Release{
+		Plugin:       "scm-mail-plugin",
+		Version:      "2.0.0",
+		Url:          artifact,
+		Checksum:     contentChecksum,
+		Dependencies: Dependencies{{Name: "scm-review-plugin", Versions: MustParseVersionRange(">=2.0.0")}},
+		Conditions:   Conditions{MinVersion: "2.0.0"},
+	}
+	assert.NoError(t, writeYml(filepath.Join(source, "scm-mail-plugin", "releases", "2-0-0.yml"), release))
+	return source
+}
+
+func TestMirrorFromDescriptorDirectory(t *testing.T) {
+	source := createMirrorSource(t, "content")
+	target := t.TempDir()
+
+	var out bytes.Buffer
+	exitCode := runMirror([]string{"-source", source, "-target", target}, &out)
+
+	assert.Equal(t, 0, exitCode, out.String())
+	assert.Contains(t, out.String(), "skipping problem in source:")
+	assert.Contains(t, out.String(), "mirrored 1 releases, 0 were up to date, 0 failed")
+
+	plugins, problems, err := scanDirectory(target)
+	assert.NoError(t, err)
+	// the dependency is not part of the source
+	assert.Len(t, problems, 1)
+	assert.Len(t, plugins, 1)
+	assert.Equal(t, "Mail", plugins[0].DisplayName)
+	release := plugins[0].Releases[0]
+	assert.Equal(t, "file://"+filepath.Join(target, "scm-mail-plugin", "artifacts", "scm-mail-plugin-2.0.0.smp"), release.Url)
+	assert.Equal(t, ">=2.0.0", release.Dependencies[0].Versions.Value)
+	assert.Equal(t, "2.0.0", release.Conditions.MinVersion)
+
+	store, err := NewArtifactStore(Configuration{ArtifactDirectory: target})
+	assert.NoError(t, err)
+	assert.Equal(t, "content", readArtifact(t, store, release.Url))
+}
+
+func TestMirrorIsIncremental(t *testing.T) {
+	source := createMirrorSource(t, "content")
+	target := t.TempDir()
+	assert.Equal(t, 0, runMirror([]string{"-source", source, "-target", target}, &bytes.Buffer{}))
+
+	var out bytes.Buffer
+	exitCode := runMirror([]string{"-source", source, "-target", target, "-url-prefix", "https://mirror.example.com/"}, &out)
+
+	assert.Equal(t, 0, exitCode, out.String())
+	assert.Contains(t, out.String(), "mirrored 0 releases, 1 were up to date, 0 failed")
+	data, err := ioutil.ReadFile(filepath.Join(target, "scm-mail-plugin", "releases", "2-0-0.yml"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "url: https://mirror.example.com/scm-mail-plugin/artifacts/scm-mail-plugin-2.0.0.smp")
+}
+
+func TestMirrorRejectsArtifactWithWrongChecksum(t *testing.T) {
+	source := createMirrorSource(t, "tampered")
+	target := t.TempDir()
+
+	var out bytes.Buffer
+	exitCode := runMirror([]string{"-source", source, "-target", target}, &out)
+
+	assert.Equal(t, 1, exitCode)
+	assert.Contains(t, out.String(), "does not match")
+	_, err := os.Stat(filepath.Join(target, "scm-mail-plugin", "artifacts", "scm-mail-plugin-2.0.0.smp"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestMirrorFromPluginCenter(t *testing.T) {
+	source := createMirrorSource(t, "content")
+	plugins, _, err := scanDirectory(source)
+	assert.NoError(t, err)
+	plugins[0].AvatarUrl = "/images/mail.png"
+	plugins[0].Translations = map[string]PluginTranslation{"de": {DisplayName: "E-Mail"}}
+	yanked := plugins[0].Releases[0]
+	yanked.Version = "1.0.0"
+	yanked.Channel = ChannelBeta
+	yanked.Yanked = true
+	yanked.Rollout = &Rollout{Percentage: 50}
+	yanked.Advisories = []Advisory{{Id: "CVE-1", Severity: SeverityHigh, Description: "broken", FixedIn: "2.0.0"}}
+	plugins[0].Releases = append(plugins[0].Releases, yanked)
+	catalog := staticCatalog(NewCatalog(plugins, nil))
+	store, err := NewArtifactStore(Configuration{ArtifactDirectory: filepath.Dir(yanked.Url)})
+	assert.NoError(t, err)
+
+	router := mux.NewRouter()
+	router.Handle("/api/v1/plugins/{version}", NewPluginHandler(catalog))
+	router.Handle("/api/v1/plugin/{name}", NewPluginDetailHandler(catalog))
+	router.Handle("/api/v1/download/{plugin}/{version}", NewDownloadHandler(catalog, store, nil, DownloadStrategies{}))
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	target := t.TempDir()
+	var out bytes.Buffer
+	exitCode := runMirror([]string{"-source", server.URL + "/api/v1/plugins/2.0.0", "-target", target}, &out)
+
+	assert.Equal(t, 0, exitCode, out.String())
+	assert.Contains(t, out.String(), "mirrored 2 releases")
+	mirrored, problems, err := scanDirectory(target)
+	assert.NoError(t, err)
+	// the dependency is not part of the source
+	assert.Len(t, problems, 2)
+	assert.Len(t, mirrored, 1)
+	assert.Equal(t, "https://scm-manager.org/img//images/mail.png", mirrored[0].AvatarUrl)
+	assert.Equal(t, "E-Mail", mirrored[0].Translations["de"].DisplayName)
+	assert.Len(t, mirrored[0].Releases, 2)
+
+	release := mirrored[0].Releases[0]
+	assert.Equal(t, "2.0.0", release.Version)
+	assert.Equal(t, contentChecksum, release.Checksum)
+	assert.Equal(t, "2.0.0", release.Conditions.MinVersion)
+	assert.Equal(t, ">=2.0.0", release.Dependencies[0].Versions.Value)
+
+	release = mirrored[0].Releases[1]
+	assert.Equal(t, "1.0.0", release.Version)
+	assert.Equal(t, ChannelBeta, release.Channel)
+	assert.True(t, release.Yanked)
+	assert.Equal(t, &Rollout{Percentage: 50}, release.Rollout)
+	assert.Equal(t, yanked.Advisories, release.Advisories)
+}
+
+func TestPluginDetailUrl(t *testing.T) {
+	detailUrl, err := pluginDetailUrl("https://plugins.example.com/center/api/v1/plugins/2.0.0?os=linux", "scm-mail-plugin")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://plugins.example.com/center/api/v1/plugin/scm-mail-plugin", detailUrl)
+
+	_, err = pluginDetailUrl("https://plugins.example.com/plugins.json", "scm-mail-plugin")
+	assert.Error(t, err)
+}
+
+func TestMirrorRequiresSourceAndTarget(t *testing.T) {
+	assert.Equal(t, 2, runMirror([]string{"-target", t.TempDir()}, &bytes.Buffer{}))
+}
+
+func TestMirrorRejectsPathsOutsideOfTarget(t *testing.T) {
+	parent := t.TempDir()
+	target := filepath.Join(parent, "target")
+	m, err := newMirror(target, "", "")
+	assert.NoError(t, err)
+	release := Release{Version: "1.0.0", Url: "https://download.scm-manager.org/a.smp", Checksum: contentChecksum}
+
+	result := m.mirrorPlugins([]Plugin{
+		{Name: "../escaped", Releases: []Release{release}},
+		{Name: "scm-mail-plugin", Releases: []Release{{Version: "1.0.0/../../../../escaped", Url: release.Url, Checksum: contentChecksum}}},
+	})
+
+	assert.Len(t, result.Failures, 2)
+	assert.Contains(t, result.Failures[0], "../escaped is not a valid plugin name")
+	assert.Contains(t, result.Failures[1], "is not a valid version")
+	files, err := ioutil.ReadDir(parent)
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+	_, err = os.Stat(filepath.Join(target, "scm-mail-plugin", "releases"))
+	assert.True(t, os.IsNotExist(err))
+}
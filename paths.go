@@ -0,0 +1,26 @@
+package main
+
+import (
+	"github.com/pkg/errors"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var pluginNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+// isValidPluginName returns true, if the name can be used as directory name for the descriptors of the plugin.
+func isValidPluginName(name string) bool {
+	return pluginNamePattern.MatchString(name)
+}
+
+// joinWithin joins the elements to the directory and fails, if the resulting path is not inside of the directory.
+// It has to be used for every path which is built from names or versions of untrusted input.
+func joinWithin(directory string, elements ...string) (string, error) {
+	path := filepath.Join(append([]string{directory}, elements...)...)
+	relative, err := filepath.Rel(directory, path)
+	if err != nil || relative == "." || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+		return "", errors.Errorf("path %s is not inside of %s", filepath.Join(elements...), directory)
+	}
+	return path, nil
+}
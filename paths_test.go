@@ -0,0 +1,29 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsValidPluginName(t *testing.T) {
+	assert.True(t, isValidPluginName("scm-mail-plugin"))
+	assert.True(t, isValidPluginName("scm-plugin_2.x"))
+	assert.False(t, isValidPluginName(""))
+	assert.False(t, isValidPluginName(".."))
+	assert.False(t, isValidPluginName("../scm-mail-plugin"))
+	assert.False(t, isValidPluginName("scm/mail"))
+}
+
+func TestJoinWithin(t *testing.T) {
+	directory := t.TempDir()
+
+	path, err := joinWithin(directory, "scm-mail-plugin", "releases", "2-0-0.yml")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(directory, "scm-mail-plugin", "releases", "2-0-0.yml"), path)
+
+	for _, elements := range [][]string{{".."}, {"scm-mail-plugin", "../../etc/passwd"}, {"a", "..", ".."}, {""}} {
+		_, err := joinWithin(directory, elements...)
+		assert.Error(t, err, elements)
+	}
+}
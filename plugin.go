@@ -1,31 +1,189 @@
 package main
 
+import (
+	"github.com/blang/semver/v4"
+)
+
+const (
+	ChannelStable  = "stable"
+	ChannelBeta    = "beta"
+	ChannelNightly = "nightly"
+)
+
+// channelRanks orders the channels by stability, a request for a channel includes all more stable channels
+var channelRanks = map[string]int{
+	ChannelStable:  0,
+	ChannelBeta:    1,
+	ChannelNightly: 2,
+}
+
+func IsValidChannel(channel string) bool {
+	_, ok := channelRanks[channel]
+	return ok
+}
+
 type Conditions struct {
-	Os         []string `yaml:"os"`
-	Arch       string   `yaml:"arch"`
-	MinVersion string   `yaml:"minVersion"`
+	Os         []string `yaml:"os,omitempty"`
+	Arch       string   `yaml:"arch,omitempty"`
+	MinVersion string   `yaml:"minVersion,omitempty"`
+	// MaxVersion is the highest compatible version of SCM-Manager (inclusive).
+	// Max versions are compared only with the precision they are specified with,
+	// so a MaxVersion of 2.0 includes 2.0.1 and a MaxJavaVersion of 11 includes 11.0.20.
+	MaxVersion string `yaml:"maxVersion,omitempty"`
+	// Versions is a range of compatible versions of SCM-Manager, e.g. ">=2.0.0 <3.0.0"
+	Versions VersionRange `yaml:"versions,omitempty"`
+	// MinJavaVersion and MaxJavaVersion are the lowest and highest compatible java versions (inclusive)
+	MinJavaVersion string `yaml:"minJavaVersion,omitempty"`
+	MaxJavaVersion string `yaml:"maxJavaVersion,omitempty"`
 }
 
 type Release struct {
-	Version              string     `yaml:"tag"`
-	Conditions           Conditions `yaml:"conditions"`
-	Dependencies         []string   `yaml:"dependencies"`
-	OptionalDependencies []string   `yaml:"optionalDependencies"`
-	Url                  string     `yaml:"url"`
-	Date                 string     `yaml:"date"`
-	Checksum             string     `yaml:"checksum"`
-	InstallLink          string     `yaml:"installLink"`
+	Plugin               string       `yaml:"plugin,omitempty"`
+	Version              string       `yaml:"tag,omitempty"`
+	Conditions           Conditions   `yaml:"conditions,omitempty"`
+	Dependencies         Dependencies `yaml:"dependencies,omitempty"`
+	OptionalDependencies Dependencies `yaml:"optionalDependencies,omitempty"`
+	Url                  string       `yaml:"url,omitempty"`
+	Date                 string       `yaml:"date,omitempty"`
+	Checksum             string       `yaml:"checksum,omitempty"`
+	InstallLink          string       `yaml:"installLink,omitempty"`
+	Channel              string       `yaml:"channel,omitempty"`
+	Rollout              *Rollout     `yaml:"rollout,omitempty"`
+	// Yanked releases are no longer offered, but can still be downloaded
+	Yanked     bool       `yaml:"yanked,omitempty"`
+	Advisories []Advisory `yaml:"advisories,omitempty"`
+	// file from which the release was read
+	file string
+}
+
+func (r Release) GetChannel() string {
+	if r.Channel == "" {
+		return ChannelStable
+	}
+	return r.Channel
+}
+
+// IsInChannel returns true, if the release is published in the given channel or in a more stable one.
+func (r Release) IsInChannel(channel string) bool {
+	rank, ok := channelRanks[r.GetChannel()]
+	return ok && rank <= channelRanks[channel]
+}
+
+func (r Release) AllDependencies() Dependencies {
+	var dependencies Dependencies
+	dependencies = append(dependencies, r.Dependencies...)
+	return append(dependencies, r.OptionalDependencies...)
+}
+
+// Dependency is declared either as plain plugin name or as name with a range of accepted versions.
+type Dependency struct {
+	Name     string       `yaml:"name,omitempty"`
+	Versions VersionRange `yaml:"versions,omitempty"`
+}
+
+func (d *Dependency) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var name string
+	if err := unmarshal(&name); err == nil {
+		d.Name = name
+		return nil
+	}
+	type plain Dependency
+	return unmarshal((*plain)(d))
+}
+
+func (d Dependency) MarshalYAML() (interface{}, error) {
+	if !d.HasVersions() {
+		return d.Name, nil
+	}
+	type plain Dependency
+	return plain(d), nil
+}
+
+func (d Dependency) HasVersions() bool {
+	return d.Versions.Value != ""
+}
+
+func (d Dependency) IsSatisfiedBy(version string) bool {
+	if !d.HasVersions() {
+		return true
+	}
+	v, err := semver.ParseTolerant(version)
+	if err != nil {
+		return false
+	}
+	return d.Versions.Contains(Version{Version: v})
+}
+
+type Dependencies []Dependency
+
+func (d Dependencies) Names() []string {
+	names := []string{}
+	for _, dependency := range d {
+		names = append(names, dependency.Name)
+	}
+	return names
+}
+
+func (d Dependencies) Versions() map[string]string {
+	versions := make(map[string]string)
+	for _, dependency := range d {
+		if dependency.HasVersions() {
+			versions[dependency.Name] = dependency.Versions.Value
+		}
+	}
+	return versions
+}
+
+// PluginTranslation contains the texts of a plugin in another language.
+type PluginTranslation struct {
+	DisplayName   string `yaml:"displayName,omitempty" json:"displayName,omitempty"`
+	Description   string `yaml:"description,omitempty" json:"description,omitempty"`
+	CategoryLabel string `yaml:"categoryLabel,omitempty" json:"categoryLabel,omitempty"`
 }
 
 type Plugin struct {
-	Name        string `yaml:"name"`
-	DisplayName string `yaml:"displayName"`
-	Description string `yaml:"description"`
-	Category    string `yaml:"category"`
-	Releases    []Release
-	Author      string `yaml:"author"`
-	Type        string `yaml:"type"`
-	AvatarUrl   string `yaml:"avatarUrl"`
+	Name          string    `yaml:"name,omitempty"`
+	DisplayName   string    `yaml:"displayName,omitempty"`
+	Description   string    `yaml:"description,omitempty"`
+	Category      string    `yaml:"category,omitempty"`
+	CategoryLabel string    `yaml:"categoryLabel,omitempty"`
+	Releases      []Release `yaml:"releases,omitempty"`
+	Author        string    `yaml:"author,omitempty"`
+	Type          string    `yaml:"type,omitempty"`
+	AvatarUrl     string    `yaml:"avatarUrl,omitempty"`
+	// Translations are read from the plugin.yml or from plugin_<lang>.yml files next to it
+	Translations map[string]PluginTranslation `yaml:"translations,omitempty"`
+}
+
+// Localize returns a copy of the plugin with the texts of the translation which matches the languages best.
+// Texts which are not translated are kept.
+func (p Plugin) Localize(languages []string) Plugin {
+	var available []string
+	for language := range p.Translations {
+		available = append(available, language)
+	}
+	// the texts of the plugin itself are english, so that english is not matched with another translation
+	if _, ok := findLanguage(available, defaultLanguage); !ok {
+		available = append(available, defaultLanguage)
+	}
+	language, ok := matchLanguage(languages, available)
+	if !ok {
+		return p
+	}
+	translation, ok := p.Translations[language]
+	if !ok {
+		return p
+	}
+	if translation.DisplayName != "" {
+		p.DisplayName = translation.DisplayName
+	}
+	if translation.Description != "" {
+		p.Description = translation.Description
+	}
+	if translation.CategoryLabel != "" {
+		p.CategoryLabel = translation.CategoryLabel
+	}
+	return p
 }
 
 func (p Plugin) GetType() string {
@@ -35,6 +193,15 @@ func (p Plugin) GetType() string {
 	return p.Type
 }
 
+func (p Plugin) FindRelease(version string) (Release, bool) {
+	for _, release := range p.Releases {
+		if release.Version == version {
+			return release, true
+		}
+	}
+	return Release{}, false
+}
+
 func (p Plugin) RequiresAuthentication() bool {
 	return p.GetType() != "SCM"
 }
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+type ReleaseDetail struct {
+	Version                    string            `json:"version"`
+	Date                       string            `json:"date"`
+	Checksum                   string            `json:"sha256sum"`
+	Channel                    string            `json:"channel"`
+	Rollout                    *Rollout          `json:"rollout,omitempty"`
+	Yanked                     bool              `json:"yanked"`
+	Advisories                 []Advisory        `json:"advisories,omitempty"`
+	Conditions                 ConditionMap      `json:"conditions"`
+	Dependencies               []string          `json:"dependencies"`
+	OptionalDependencies       []string          `json:"optionalDependencies"`
+	DependencyVersions         map[string]string `json:"dependencyVersions,omitempty"`
+	OptionalDependencyVersions map[string]string `json:"optionalDependencyVersions,omitempty"`
+	Links                      Links             `json:"_links"`
+}
+
+type PluginDetail struct {
+	Name          string          `json:"name"`
+	DisplayName   string          `json:"displayName"`
+	Description   string          `json:"description"`
+	Category      string          `json:"category"`
+	CategoryLabel string          `json:"categoryLabel,omitempty"`
+	Author        string          `json:"author"`
+	Type          string          `json:"type"`
+	AvatarUrl     string          `json:"avatarUrl"`
+	PluginSets    []string        `json:"pluginSets"`
+	Releases      []ReleaseDetail `json:"releases"`
+	// Translations are returned untouched, so that the plugin center can be mirrored
+	Translations map[string]PluginTranslation `json:"translations,omitempty"`
+}
+
+func NewPluginDetailHandler(catalogHolder *CatalogHolder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		catalog := catalogHolder.Get()
+
+		pluginName := mux.Vars(r)["name"]
+		plugin, ok := catalog.FindPlugin(pluginName)
+		if !ok {
+			writeJsonError(w, http.StatusNotFound, fmt.Sprintf("no plugin found for name %s", pluginName))
+			return
+		}
+
+		authenticated := r.Context().Value("subject") != nil
+		plugin = plugin.Localize(requestedLanguages(r.URL.Query().Get("lang"), r.Header.Get("Accept-Language")))
+		writeJson(w, http.StatusOK, createPluginDetail(catalog, plugin, NewUrlGenerator(*r), authenticated))
+	}
+}
+
+func createPluginDetail(catalog *Catalog, plugin Plugin, generator UrlGenerator, authenticated bool) PluginDetail {
+	detail := PluginDetail{
+		Name:          plugin.Name,
+		DisplayName:   plugin.DisplayName,
+		Description:   plugin.Description,
+		Category:      plugin.Category,
+		CategoryLabel: plugin.CategoryLabel,
+		Author:        plugin.Author,
+		Type:          plugin.GetType(),
+		AvatarUrl:     createAvatarUrl(plugin),
+		PluginSets:    []string{},
+		Releases:      []ReleaseDetail{},
+		Translations:  plugin.Translations,
+	}
+
+	for _, release := range plugin.Releases {
+		detail.Releases = append(detail.Releases, createReleaseDetail(plugin, release, generator, authenticated))
+	}
+
+	for _, pluginSet := range catalog.PluginSets {
+		for _, name := range pluginSet.Plugins {
+			if name == plugin.Name {
+				detail.PluginSets = append(detail.PluginSets, pluginSet.Id)
+				break
+			}
+		}
+	}
+
+	return detail
+}
+
+func createReleaseDetail(plugin Plugin, release Release, generator UrlGenerator, authenticated bool) ReleaseDetail {
+	return ReleaseDetail{
+		Version:                    release.Version,
+		Date:                       release.Date,
+		Checksum:                   release.Checksum,
+		Channel:                    release.GetChannel(),
+		Rollout:                    release.Rollout,
+		Yanked:                     release.Yanked,
+		Advisories:                 release.Advisories,
+		Conditions:                 extractConditions(release.Conditions),
+		Dependencies:               release.Dependencies.Names(),
+		OptionalDependencies:       release.OptionalDependencies.Names(),
+		DependencyVersions:         release.Dependencies.Versions(),
+		OptionalDependencyVersions: release.OptionalDependencies.Versions(),
+		Links:                      createReleaseLinks(plugin, release, generator, authenticated),
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func readPluginDetail(t *testing.T, url string, subject string, catalog *CatalogHolder) (int, PluginDetail) {
+	rr := initRouter(t, url, subject, NewPluginDetailHandler(catalog))
+
+	var detail PluginDetail
+	if rr.Code == http.StatusOK {
+		err := json.Unmarshal(rr.Body.Bytes(), &detail)
+		assert.NoError(t, err)
+	}
+	return rr.Code, detail
+}
+
+func TestPluginDetailHandlerReturnsMetadata(t *testing.T) {
+	code, detail := readPluginDetail(t, "/api/v1/plugin/ssh-plugin", "", testCatalog())
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ssh-plugin", detail.Name)
+	assert.Equal(t, "ssh plugin", detail.DisplayName)
+	assert.Equal(t, "description for ssh plugin", detail.Description)
+	assert.Equal(t, "test", detail.Category)
+	assert.Equal(t, "Cloudogu", detail.Author)
+	assert.Equal(t, "CLOUDOGU", detail.Type)
+	assert.Equal(t, "https://scm-manager.org/img//images/ssh-logo.png", detail.AvatarUrl)
+}
+
+func TestPluginDetailHandlerReturnsAllReleases(t *testing.T) {
+	code, detail := readPluginDetail(t, "/api/v1/plugin/ssh-plugin", "trillian", testCatalog())
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, detail.Releases, 3)
+
+	release := detail.Releases[0]
+	assert.Equal(t, "2.0", release.Version)
+	assert.Equal(t, "1.01.2019", release.Date)
+	assert.Equal(t, contentChecksum, release.Checksum)
+	assert.Equal(t, ChannelStable, release.Channel)
+	assert.Equal(t, "2.0.1", release.Conditions["minVersion"])
+	assert.Equal(t, []string{"scm-mail-plugin"}, release.Dependencies)
+	assert.Equal(t, []string{"scm-review-plugin"}, release.OptionalDependencies)
+	assert.Equal(t, "http:///api/v1/download/ssh-plugin/2.0", release.Links["download"].Href)
+	assert.Equal(t, "myCloudogu.com/install/my_plugin", release.Links["install"].Href)
+}
+
+func TestPluginDetailHandlerHidesDownloadLinkWithoutAuthentication(t *testing.T) {
+	_, detail := readPluginDetail(t, "/api/v1/plugin/ssh-plugin", "", testCatalog())
+
+	assert.Equal(t, "", detail.Releases[0].Links["download"].Href)
+}
+
+func TestPluginDetailHandlerReturnsPluginSets(t *testing.T) {
+	plugin := Plugin{Name: "scm-cas-plugin"}
+	catalog := staticCatalog(NewCatalog([]Plugin{plugin}, testDataPluginSets))
+
+	_, detail := readPluginDetail(t, "/api/v1/plugin/scm-cas-plugin", "", catalog)
+
+	assert.Equal(t, []string{"administration-and-management"}, detail.PluginSets)
+	assert.Empty(t, detail.Releases)
+}
+
+func TestPluginDetailHandlerReturnsNotFoundForUnknownPlugin(t *testing.T) {
+	code, _ := readPluginDetail(t, "/api/v1/plugin/scm-unknown-plugin", "", testCatalog())
+
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestPluginDetailHandlerReturnsTranslation(t *testing.T) {
+	rr := initRouter(t, "/api/v1/plugin/ad-plugin?lang=de", "", NewPluginDetailHandler(translatedCatalog()))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"displayName":"Active Directory Plugin"`)
+	assert.Contains(t, rr.Body.String(), `"categoryLabel":"Testen"`)
+}
@@ -2,13 +2,16 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"github.com/blang/semver/v4"
 	"github.com/gorilla/mux"
 	"github.com/hashicorp/go-version"
+	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"log"
 	"net/http"
+	"sort"
 	"strconv"
 )
 
@@ -21,32 +24,57 @@ type Link struct {
 }
 
 type PluginResult struct {
-	Name                 string       `json:"name"`
-	DisplayName          string       `json:"displayName"`
-	Description          string       `json:"description"`
-	Category             string       `json:"category"`
-	Version              string       `json:"version"`
-	Author               string       `json:"author"`
-	Checksum             string       `json:"sha256sum"`
-	Type                 string       `json:"type"`
-	AvatarUrl            string       `json:"avatarUrl"`
-	Conditions           ConditionMap `json:"conditions"`
-	Dependencies         []string     `json:"dependencies"`
-	OptionalDependencies []string     `json:"optionalDependencies"`
-	Links                Links        `json:"_links"`
+	Name                       string            `json:"name"`
+	DisplayName                string            `json:"displayName"`
+	Description                string            `json:"description"`
+	Category                   string            `json:"category"`
+	CategoryLabel              string            `json:"categoryLabel,omitempty"`
+	Version                    string            `json:"version"`
+	Date                       string            `json:"date"`
+	Channel                    string            `json:"channel"`
+	Author                     string            `json:"author"`
+	Checksum                   string            `json:"sha256sum"`
+	Type                       string            `json:"type"`
+	AvatarUrl                  string            `json:"avatarUrl"`
+	Conditions                 ConditionMap      `json:"conditions"`
+	Dependencies               []string          `json:"dependencies"`
+	OptionalDependencies       []string          `json:"optionalDependencies"`
+	DependencyVersions         map[string]string `json:"dependencyVersions,omitempty"`
+	OptionalDependencyVersions map[string]string `json:"optionalDependencyVersions,omitempty"`
+	Advisories                 []AdvisoryResult  `json:"advisories,omitempty"`
+	Links                      Links             `json:"_links"`
+}
+
+// PluginSetResult is a plugin set together with the compatible release of each of its plugins.
+// A set is complete only if every plugin has a compatible release.
+type PluginSetResult struct {
+	PluginSet
+	Complete       bool            `json:"complete"`
+	MissingPlugins []string        `json:"missingPlugins"`
+	Embedded       EmbeddedObjects `json:"_embedded"`
+	Links          Links           `json:"_links"`
 }
 
 type EmbeddedObjects map[string]interface{}
 
 type Response struct {
 	Embedded EmbeddedObjects `json:"_embedded"`
+	// Page and PageTotal are only set for paged responses
+	Page      *int  `json:"page,omitempty"`
+	PageTotal *int  `json:"pageTotal,omitempty"`
+	Links     Links `json:"_links,omitempty"`
 }
 
 type RequestConditions struct {
-	Os      string
-	Arch    string
-	Jre     string
-	Version version.Version
+	Os          string
+	Arch        string
+	Jre         string
+	Channel     string
+	Instance    string
+	JavaVersion *version.Version
+	Version     version.Version
+	// Languages are the requested languages ordered by preference
+	Languages []string
 }
 
 var (
@@ -54,18 +82,25 @@ var (
 		Name: "scm_plugin_center_api_requests",
 		Help: "Total number of requests",
 	}, []string{
-		"version", "os", "arch", "jre", "authenticated",
+		"version", "os", "arch", "jre", "channel", "authenticated",
 	})
 )
 
-func NewPluginHandler(plugins []Plugin, pluginSets []PluginSet) http.HandlerFunc {
+func NewPluginHandler(catalogHolder *CatalogHolder) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var pluginResults []PluginResult
+		catalog := catalogHolder.Get()
 
 		requestConditions, err := extractRequestConditions(r)
 		if err != nil {
-			log.Println("could not parse form data for request", err)
-			http.Error(w, "could not parse form data for request", http.StatusBadRequest)
+			log.Println("invalid request conditions:", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		pluginQuery, err := extractPluginQuery(r.Form)
+		if err != nil {
+			writeJsonError(w, http.StatusBadRequest, err.Error())
 			return
 		}
 
@@ -78,26 +113,35 @@ func NewPluginHandler(plugins []Plugin, pluginSets []PluginSet) http.HandlerFunc
 			requestConditions.Os,
 			requestConditions.Arch,
 			requestConditions.Jre,
+			requestConditions.Channel,
 			strconv.FormatBool(authenticated),
 		).Inc()
 
 		urlGenerator := NewUrlGenerator(*r)
 
-		for _, plugin := range plugins {
+		for _, plugin := range catalog.Plugins {
 			pluginResults = appendIfOk(pluginResults, plugin, requestConditions, urlGenerator, authenticated)
 		}
 
+		pluginResults = pluginQuery.Apply(pluginResults)
+
 		embedded := make(map[string]interface{})
-		embedded["plugins"] = pluginResults
+		embedded["plugins"] = pluginQuery.Paginate(pluginResults)
 
-		var pluginSetResults []PluginSet
+		var pluginSetResults []PluginSetResult
 
-		for _, pluginSet := range pluginSets {
-			pluginSetResults = appendPluginSetIfOk(pluginSetResults, pluginSet, requestConditions)
+		for _, pluginSet := range catalog.PluginSets {
+			pluginSetResults = appendPluginSetIfOk(pluginSetResults, catalog, pluginSet, requestConditions, urlGenerator, authenticated)
 		}
 		embedded["plugin-sets"] = pluginSetResults
 
 		response := Response{Embedded: embedded}
+		if pluginQuery.IsPaged() {
+			page, pageTotal := pluginQuery.Page, pluginQuery.PageTotal(len(pluginResults))
+			response.Page = &page
+			response.PageTotal = &pageTotal
+			response.Links = createPageLinks(urlGenerator, *r.URL, pluginQuery, len(pluginResults))
+		}
 
 		w.Header().Add("Content-Type", "application/json")
 
@@ -113,67 +157,153 @@ func NewPluginHandler(plugins []Plugin, pluginSets []PluginSet) http.HandlerFunc
 	}
 }
 
+func NewReleasesHandler(catalogHolder *CatalogHolder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		requestConditions, err := extractRequestConditions(r)
+		if err != nil {
+			log.Println("invalid request conditions:", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		pluginName := mux.Vars(r)["plugin"]
+		plugin, ok := catalogHolder.Get().FindPlugin(pluginName)
+		if !ok {
+			writeJsonError(w, http.StatusNotFound, fmt.Sprintf("no plugin found for name %s", pluginName))
+			return
+		}
+
+		authenticated := r.Context().Value("subject") != nil
+		urlGenerator := NewUrlGenerator(*r)
+
+		plugin = plugin.Localize(requestConditions.Languages)
+		releases := findCompatibleReleases(plugin, requestConditions)
+		sort.SliceStable(releases, func(i1 int, i2 int) bool { return less(releases)(i2, i1) })
+
+		releaseResults := []PluginResult{}
+		for _, release := range releases {
+			releaseResults = append(releaseResults, createPluginResult(plugin, release, urlGenerator, authenticated))
+		}
+
+		writeJson(w, http.StatusOK, Response{Embedded: EmbeddedObjects{"releases": releaseResults}})
+	}
+}
+
+// extractRequestConditions returns the conditions of the request or an error with a message for the client.
 func extractRequestConditions(r *http.Request) (RequestConditions, error) {
 	err := r.ParseForm()
 	if err != nil {
-		return RequestConditions{}, err
+		return RequestConditions{}, errors.Wrap(err, "could not parse form data for request")
 	}
 	queryParameters := r.Form
 	vars := mux.Vars(r)
 	requestVersion, err := version.NewVersion(vars["version"])
 	if err != nil {
-		return RequestConditions{}, err
+		return RequestConditions{}, errors.Errorf("%s is not a valid version", vars["version"])
 	}
 	requestConditions := RequestConditions{
-		Os:      queryParameters.Get("os"),
-		Arch:    queryParameters.Get("arch"),
-		Jre:     queryParameters.Get("jre"),
-		Version: *requestVersion,
+		Os:        queryParameters.Get("os"),
+		Arch:      queryParameters.Get("arch"),
+		Jre:       queryParameters.Get("jre"),
+		Channel:   queryParameters.Get("channel"),
+		Instance:  queryParameters.Get("instance"),
+		Version:   *requestVersion,
+		Languages: requestedLanguages(queryParameters.Get("lang"), r.Header.Get("Accept-Language")),
+	}
+	if subject, ok := r.Context().Value("subject").(*Subject); ok && requestConditions.Instance == "" {
+		requestConditions.Instance = subject.Id
+	}
+	if requestConditions.Channel == "" {
+		requestConditions.Channel = ChannelStable
+	} else if !IsValidChannel(requestConditions.Channel) {
+		return RequestConditions{}, errors.Errorf("unknown channel %s", requestConditions.Channel)
+	}
+	if requestConditions.Jre != "" {
+		javaVersion, err := parseJavaVersion(requestConditions.Jre)
+		if err != nil {
+			log.Println("could not parse jre", requestConditions.Jre, "- ignoring java version conditions")
+		} else {
+			requestConditions.JavaVersion = javaVersion
+		}
 	}
 	return requestConditions, nil
 }
 
 func appendIfOk(results []PluginResult, plugin Plugin, conditions RequestConditions, generator UrlGenerator, authenticated bool) []PluginResult {
+	release := findCompatibleRelease(plugin, conditions)
+	if release == nil {
+		return results
+	}
+	return append(results, createPluginResult(plugin.Localize(conditions.Languages), *release, generator, authenticated))
+}
+
+func findCompatibleRelease(plugin Plugin, conditions RequestConditions) *Release {
+	releases := findCompatibleReleases(plugin, conditions)
+	if len(releases) == 0 {
+		return nil
+	}
+	return &releases[0]
+}
+
+func findCompatibleReleases(plugin Plugin, conditions RequestConditions) []Release {
+	var releases []Release
 	for _, release := range plugin.Releases {
-		if conditionsMatch(conditions, release.Conditions) {
+		if releaseMatches(plugin, release, conditions) {
+			releases = append(releases, release)
+		}
+	}
+	return releases
+}
 
-			pluginType := plugin.Type
-			if pluginType == "" {
-				pluginType = "SCM"
-			}
+func releaseMatches(plugin Plugin, release Release, conditions RequestConditions) bool {
+	return !release.Yanked &&
+		release.IsInChannel(conditions.Channel) &&
+		isRolledOutTo(plugin.Name, release, conditions.Instance) &&
+		conditionsMatch(conditions, release.Conditions)
+}
 
-			downloadUrl := ""
-			if !plugin.RequiresAuthentication() || authenticated {
-				downloadUrl = generator.DownloadUrl(plugin, release.Version)
-			}
+func createPluginResult(plugin Plugin, release Release, generator UrlGenerator, authenticated bool) PluginResult {
+	return PluginResult{
+		Name:                       plugin.Name,
+		DisplayName:                plugin.DisplayName,
+		Description:                plugin.Description,
+		Category:                   plugin.Category,
+		CategoryLabel:              plugin.CategoryLabel,
+		Version:                    release.Version,
+		Date:                       release.Date,
+		Channel:                    release.GetChannel(),
+		Author:                     plugin.Author,
+		Checksum:                   release.Checksum,
+		Type:                       plugin.GetType(),
+		AvatarUrl:                  createAvatarUrl(plugin),
+		Conditions:                 extractConditions(release.Conditions),
+		Dependencies:               release.Dependencies.Names(),
+		OptionalDependencies:       release.OptionalDependencies.Names(),
+		DependencyVersions:         release.Dependencies.Versions(),
+		OptionalDependencyVersions: release.OptionalDependencies.Versions(),
+		Advisories:                 collectAdvisories(plugin),
+		Links:                      createReleaseLinks(plugin, release, generator, authenticated),
+	}
+}
 
-			avatarUrl := plugin.AvatarUrl
-			if avatarUrl != "" {
-				avatarUrl = "https://scm-manager.org/img/" + avatarUrl
-			}
-			result := PluginResult{
-				Name:                 plugin.Name,
-				DisplayName:          plugin.DisplayName,
-				Description:          plugin.Description,
-				Category:             plugin.Category,
-				Version:              release.Version,
-				Author:               plugin.Author,
-				Checksum:             release.Checksum,
-				Type:                 pluginType,
-				AvatarUrl:            avatarUrl,
-				Conditions:           extractConditions(release.Conditions),
-				Dependencies:         nullToEmpty(release.Dependencies),
-				OptionalDependencies: nullToEmpty(release.OptionalDependencies),
-				Links: Links{
-					"download": Link{Href: downloadUrl},
-					"install":  Link{Href: release.InstallLink},
-				},
-			}
-			return append(results, result)
-		}
+// createAvatarUrl resolves avatar paths against the image directory of the website,
+// absolute urls (e.g. of mirrored plugins) are returned unchanged.
+func createAvatarUrl(plugin Plugin) string {
+	if plugin.AvatarUrl == "" || isHttpUrl(plugin.AvatarUrl) {
+		return plugin.AvatarUrl
 	}
+	return "https://scm-manager.org/img/" + plugin.AvatarUrl
+}
 
-	return results
+func createReleaseLinks(plugin Plugin, release Release, generator UrlGenerator, authenticated bool) Links {
+	downloadUrl := ""
+	if !plugin.RequiresAuthentication() || authenticated {
+		downloadUrl = generator.DownloadUrl(plugin, release.Version)
+	}
+	return Links{
+		"download": Link{Href: downloadUrl},
+		"install":  Link{Href: release.InstallLink},
+	}
 }
 
 func conditionsMatch(requestConditions RequestConditions, releaseConditions Conditions) bool {
@@ -191,15 +321,65 @@ func conditionsMatch(requestConditions RequestConditions, releaseConditions Cond
 	if releaseConditions.Arch != "" && requestConditions.Arch != "" && releaseConditions.Arch != requestConditions.Arch {
 		return false
 	}
-	if releaseConditions.MinVersion == "" {
-		return true
+	if releaseConditions.MinVersion != "" {
+		minVersion, err := version.NewVersion(releaseConditions.MinVersion)
+		if err != nil {
+			log.Println("could not parse version string", releaseConditions.MinVersion, "- ignoring release")
+			return false
+		}
+		if !requestConditions.Version.GreaterThanOrEqual(minVersion) {
+			return false
+		}
 	}
-	minVersion, err := version.NewVersion(releaseConditions.MinVersion)
-	if err != nil {
-		log.Println("could not parse version string", releaseConditions.MinVersion, "- ignoring release")
+	if releaseConditions.MaxVersion != "" {
+		ok, err := versionAtMost(&requestConditions.Version, releaseConditions.MaxVersion)
+		if err != nil {
+			log.Println("could not parse version string", releaseConditions.MaxVersion, "- ignoring release")
+			return false
+		}
+		if !ok {
+			return false
+		}
+	}
+	if !javaVersionMatches(requestConditions, releaseConditions) {
 		return false
 	}
-	return requestConditions.Version.GreaterThanOrEqual(minVersion)
+	if releaseConditions.Versions.Value != "" {
+		v, err := semver.ParseTolerant(requestConditions.Version.String())
+		if err != nil {
+			log.Println("could not parse request version", requestConditions.Version.String(), "as semantic version - ignoring release")
+			return false
+		}
+		return releaseConditions.Versions.Contains(Version{Version: v})
+	}
+	return true
+}
+
+func javaVersionMatches(requestConditions RequestConditions, releaseConditions Conditions) bool {
+	if requestConditions.JavaVersion == nil {
+		return true
+	}
+	if releaseConditions.MinJavaVersion != "" {
+		minJavaVersion, err := parseJavaVersion(releaseConditions.MinJavaVersion)
+		if err != nil {
+			log.Println("could not parse java version string", releaseConditions.MinJavaVersion, "- ignoring release")
+			return false
+		}
+		if !requestConditions.JavaVersion.GreaterThanOrEqual(minJavaVersion) {
+			return false
+		}
+	}
+	if releaseConditions.MaxJavaVersion != "" {
+		ok, err := javaVersionAtMost(requestConditions.JavaVersion, releaseConditions.MaxJavaVersion)
+		if err != nil {
+			log.Println("could not parse java version string", releaseConditions.MaxJavaVersion, "- ignoring release")
+			return false
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
 }
 
 func extractConditions(conditions Conditions) ConditionMap {
@@ -213,18 +393,22 @@ func extractConditions(conditions Conditions) ConditionMap {
 	if conditions.MinVersion != "" {
 		conditionMap["minVersion"] = conditions.MinVersion
 	}
-	return conditionMap
-}
-
-func nullToEmpty(strings []string) []string {
-	if strings == nil {
-		return []string{}
-	} else {
-		return strings
+	if conditions.MaxVersion != "" {
+		conditionMap["maxVersion"] = conditions.MaxVersion
+	}
+	if conditions.Versions.Value != "" {
+		conditionMap["versions"] = conditions.Versions.Value
 	}
+	if conditions.MinJavaVersion != "" {
+		conditionMap["minJavaVersion"] = conditions.MinJavaVersion
+	}
+	if conditions.MaxJavaVersion != "" {
+		conditionMap["maxJavaVersion"] = conditions.MaxJavaVersion
+	}
+	return conditionMap
 }
 
-func appendPluginSetIfOk(results []PluginSet, pluginSet PluginSet, conditions RequestConditions) []PluginSet {
+func appendPluginSetIfOk(results []PluginSetResult, catalog *Catalog, pluginSet PluginSet, conditions RequestConditions, generator UrlGenerator, authenticated bool) []PluginSetResult {
 	v, err := semver.New(conditions.Version.String())
 	if err != nil {
 		return results
@@ -232,5 +416,38 @@ func appendPluginSetIfOk(results []PluginSet, pluginSet PluginSet, conditions Re
 	if !pluginSet.Versions.Contains(Version{Version: *v}) {
 		return results
 	}
-	return append(results, pluginSet)
+	return append(results, createPluginSetResult(catalog, pluginSet, conditions, generator, authenticated))
+}
+
+func createPluginSetResult(catalog *Catalog, pluginSet PluginSet, conditions RequestConditions, generator UrlGenerator, authenticated bool) PluginSetResult {
+	pluginResults := []PluginResult{}
+	missingPlugins := []string{}
+	for _, name := range pluginSet.Plugins {
+		plugin, ok := catalog.FindPlugin(name)
+		if !ok {
+			missingPlugins = append(missingPlugins, name)
+			continue
+		}
+		release := findCompatibleRelease(plugin, conditions)
+		if release == nil {
+			missingPlugins = append(missingPlugins, name)
+			continue
+		}
+		pluginResults = append(pluginResults, createPluginResult(plugin.Localize(conditions.Languages), *release, generator, authenticated))
+	}
+	return PluginSetResult{
+		PluginSet:      pluginSet,
+		Complete:       len(missingPlugins) == 0,
+		MissingPlugins: missingPlugins,
+		Embedded:       EmbeddedObjects{"plugins": pluginResults},
+		Links:          createPluginSetLinks(pluginSet, generator),
+	}
+}
+
+func createPluginSetLinks(pluginSet PluginSet, generator UrlGenerator) Links {
+	links := Links{}
+	for name := range pluginSet.Images {
+		links[name] = Link{Href: generator.PluginSetImageUrl(pluginSet, name)}
+	}
+	return links
 }
@@ -4,11 +4,12 @@ import (
 	"encoding/json"
 	"github.com/stretchr/testify/assert"
 	"net/http"
+	"strings"
 	"testing"
 )
 
 func TestPluginHandlerHasEmbeddedCollections(t *testing.T) {
-	rr := initRouter(t, "/api/v1/plugins/2.0.1?os=linux&arch=64", "", NewPluginHandler(testData, testDataPluginSets))
+	rr := initRouter(t, "/api/v1/plugins/2.0.1?os=linux&arch=64", "", NewPluginHandler(testCatalog()))
 
 	assert.Equal(t, http.StatusOK, rr.Code)
 
@@ -20,7 +21,7 @@ func TestPluginHandlerHasEmbeddedCollections(t *testing.T) {
 }
 
 func TestPluginHandlerReturnsLatestPluginRelease(t *testing.T) {
-	rr := initRouter(t, "/api/v1/plugins/2.0.1?os=linux&arch=64", "", NewPluginHandler(testData, testDataPluginSets))
+	rr := initRouter(t, "/api/v1/plugins/2.0.1?os=linux&arch=64", "", NewPluginHandler(testCatalog()))
 
 	assert.Equal(t, http.StatusOK, rr.Code)
 
@@ -30,11 +31,11 @@ func TestPluginHandlerReturnsLatestPluginRelease(t *testing.T) {
 	assert.Contains(t, rr.Body.String(), `"category":"test"`)
 	assert.Contains(t, rr.Body.String(), `"version":"2.0"`)
 	assert.Contains(t, rr.Body.String(), `"author":"Cloudogu"`)
-	assert.Contains(t, rr.Body.String(), `"sha256sum":"abc"`)
+	assert.Contains(t, rr.Body.String(), `"sha256sum":"`+contentChecksum+`"`)
 }
 
 func TestPluginHandlerReturnsConditionsFromRelease(t *testing.T) {
-	rr := initRouter(t, "/api/v1/plugins/2.0.1?os=linux&arch=64", "", NewPluginHandler(testData, testDataPluginSets))
+	rr := initRouter(t, "/api/v1/plugins/2.0.1?os=linux&arch=64", "", NewPluginHandler(testCatalog()))
 
 	assert.Equal(t, http.StatusOK, rr.Code)
 
@@ -45,7 +46,7 @@ func TestPluginHandlerReturnsConditionsFromRelease(t *testing.T) {
 }
 
 func TestPluginHandlerReturnsDependenciesFromRelease(t *testing.T) {
-	rr := initRouter(t, "/api/v1/plugins/2.0.1?os=linux&arch=64", "", NewPluginHandler(testData, testDataPluginSets))
+	rr := initRouter(t, "/api/v1/plugins/2.0.1?os=linux&arch=64", "", NewPluginHandler(testCatalog()))
 
 	assert.Equal(t, http.StatusOK, rr.Code)
 
@@ -53,8 +54,23 @@ func TestPluginHandlerReturnsDependenciesFromRelease(t *testing.T) {
 	assert.Contains(t, rr.Body.String(), `"optionalDependencies":["scm-review-plugin"]`)
 }
 
+func TestPluginHandlerReturnsDependencyVersionsFromRelease(t *testing.T) {
+	plugin := testData[1]
+	plugin.Releases = []Release{{
+		Version:      "1.0",
+		Dependencies: Dependencies{{Name: "scm-mail-plugin", Versions: MustParseVersionRange(">=2.3.0")}},
+	}}
+
+	rr := initRouter(t, "/api/v1/plugins/2.0.1", "", NewPluginHandler(staticCatalog(NewCatalog([]Plugin{plugin}, nil))))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"dependencies":["scm-mail-plugin"]`)
+	assert.Contains(t, rr.Body.String(), `"dependencyVersions":{"scm-mail-plugin":"\u003e=2.3.0"}`)
+	assert.NotContains(t, rr.Body.String(), `"optionalDependencyVersions"`)
+}
+
 func TestPluginHandlerReturnsEmptyDependenciesWhenNotSetInRelease(t *testing.T) {
-	rr := initRouter(t, "/api/v1/plugins/1.0.0?os=windows", "", NewPluginHandler(testData, testDataPluginSets))
+	rr := initRouter(t, "/api/v1/plugins/1.0.0?os=windows", "", NewPluginHandler(testCatalog()))
 
 	assert.Equal(t, http.StatusOK, rr.Code)
 
@@ -62,7 +78,7 @@ func TestPluginHandlerReturnsEmptyDependenciesWhenNotSetInRelease(t *testing.T)
 }
 
 func TestPluginHandlerFiltersForScmVersion(t *testing.T) {
-	rr := initRouter(t, "/api/v1/plugins/2.0.0?os=linux&arch=64", "", NewPluginHandler(testData, testDataPluginSets))
+	rr := initRouter(t, "/api/v1/plugins/2.0.0?os=linux&arch=64", "", NewPluginHandler(testCatalog()))
 
 	assert.Equal(t, http.StatusOK, rr.Code)
 
@@ -75,7 +91,7 @@ func TestPluginHandlerFiltersForScmVersion(t *testing.T) {
 }
 
 func TestPluginHandlerFiltersForOs(t *testing.T) {
-	rr := initRouter(t, "/api/v1/plugins/2.0.1?os=windows&arch=64", "", NewPluginHandler(testData, testDataPluginSets))
+	rr := initRouter(t, "/api/v1/plugins/2.0.1?os=windows&arch=64", "", NewPluginHandler(testCatalog()))
 
 	assert.Equal(t, http.StatusOK, rr.Code)
 
@@ -84,7 +100,7 @@ func TestPluginHandlerFiltersForOs(t *testing.T) {
 }
 
 func TestPluginHandlerFiltersForArch(t *testing.T) {
-	rr := initRouter(t, "/api/v1/plugins/2.0.1?os=linux&arch=32", "", NewPluginHandler(testData, testDataPluginSets))
+	rr := initRouter(t, "/api/v1/plugins/2.0.1?os=linux&arch=32", "", NewPluginHandler(testCatalog()))
 
 	assert.Equal(t, http.StatusOK, rr.Code)
 
@@ -92,7 +108,7 @@ func TestPluginHandlerFiltersForArch(t *testing.T) {
 }
 
 func TestPluginHandlerTreatsOsAndArchAsOptional(t *testing.T) {
-	rr := initRouter(t, "/api/v1/plugins/2.0.1", "", NewPluginHandler(testData, testDataPluginSets))
+	rr := initRouter(t, "/api/v1/plugins/2.0.1", "", NewPluginHandler(testCatalog()))
 
 	assert.Equal(t, http.StatusOK, rr.Code)
 
@@ -101,7 +117,7 @@ func TestPluginHandlerTreatsOsAndArchAsOptional(t *testing.T) {
 }
 
 func TestPluginHandlerRewritesDownloadUrl(t *testing.T) {
-	rr := initRouter(t, "/api/v1/plugins/2.0.1", "", NewPluginHandler(testData, testDataPluginSets))
+	rr := initRouter(t, "/api/v1/plugins/2.0.1", "", NewPluginHandler(testCatalog()))
 
 	assert.Equal(t, http.StatusOK, rr.Code)
 
@@ -111,7 +127,7 @@ func TestPluginHandlerRewritesDownloadUrl(t *testing.T) {
 }
 
 func TestPluginHandlerGetsRightDataForCloudoguPlugin(t *testing.T) {
-	rr := initRouter(t, "/api/v1/plugins/2.0.1", "", NewPluginHandler(testData, testDataPluginSets))
+	rr := initRouter(t, "/api/v1/plugins/2.0.1", "", NewPluginHandler(testCatalog()))
 
 	assert.Equal(t, http.StatusOK, rr.Code)
 
@@ -122,7 +138,7 @@ func TestPluginHandlerGetsRightDataForCloudoguPlugin(t *testing.T) {
 }
 
 func TestPluginHandlerReturnsPluginsSets(t *testing.T) {
-	rr := initRouter(t, "/api/v1/plugins/2.0.0?os=linux&arch=64", "", NewPluginHandler(testData, testDataPluginSets))
+	rr := initRouter(t, "/api/v1/plugins/2.0.0?os=linux&arch=64", "", NewPluginHandler(testCatalog()))
 
 	assert.Equal(t, http.StatusOK, rr.Code)
 
@@ -137,4 +153,329 @@ func TestPluginHandlerReturnsPluginsSets(t *testing.T) {
 	assert.Contains(t, rr.Body.String(), `"plugins":["scm-editor-plugin","scm-readme-plugin"]`)
 	assert.Contains(t, rr.Body.String(), `"de":{"name":"Anklicken und loslegen","features":["Merkmal 1","Merkmal 2","Merkmal 3"]`)
 	assert.Contains(t, rr.Body.String(), `"en":{"name":"Plug'n Play","features":["Feature 1","Feature 2","Feature 3"]`)
+	assert.Contains(t, rr.Body.String(), `"_links":{"check":{"href":"http://`)
+	assert.Contains(t, rr.Body.String(), `/api/v1/plugin-sets/plug-and-play/images/check"}}`)
+	assert.NotContains(t, rr.Body.String(), `"images"`)
+}
+
+func TestReleasesHandlerReturnsAllCompatibleReleases(t *testing.T) {
+	rr := initRouter(t, "/api/v1/plugins/2.0.1/ssh-plugin/releases?os=linux&arch=64", "trillian", NewReleasesHandler(testCatalog()))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var response struct {
+		Embedded struct {
+			Releases []PluginResult `json:"releases"`
+		} `json:"_embedded"`
+	}
+	err := json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.NoError(t, err)
+
+	releases := response.Embedded.Releases
+	assert.Len(t, releases, 3)
+	assert.Equal(t, "2.0", releases[0].Version)
+	assert.Equal(t, "1.1", releases[1].Version)
+	assert.Equal(t, "0.1", releases[2].Version)
+	assert.Equal(t, "1.01.2019", releases[1].Date)
+	assert.Equal(t, contentChecksum, releases[1].Checksum)
+	assert.Equal(t, "http:///api/v1/download/ssh-plugin/1.1", releases[1].Links["download"].Href)
+}
+
+func TestReleasesHandlerFiltersIncompatibleReleases(t *testing.T) {
+	rr := initRouter(t, "/api/v1/plugins/2.0.0/ssh-plugin/releases?os=linux&arch=32", "", NewReleasesHandler(testCatalog()))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NotContains(t, rr.Body.String(), `"version":"2.0"`)
+	assert.NotContains(t, rr.Body.String(), `"version":"1.1"`)
+	assert.Contains(t, rr.Body.String(), `"version":"0.1"`)
+}
+
+func TestReleasesHandlerSortsReleases(t *testing.T) {
+	plugin := Plugin{
+		Name: "scm-unsorted-plugin",
+		Releases: []Release{
+			{Version: "1.2.0"},
+			{Version: "1.10.0"},
+			{Version: "1.9.0"},
+		},
+	}
+
+	rr := initRouter(t, "/api/v1/plugins/2.0.0/scm-unsorted-plugin/releases", "", NewReleasesHandler(staticCatalog(NewCatalog([]Plugin{plugin}, nil))))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	body := rr.Body.String()
+	assert.Less(t, strings.Index(body, `"1.10.0"`), strings.Index(body, `"1.9.0"`))
+	assert.Less(t, strings.Index(body, `"1.9.0"`), strings.Index(body, `"1.2.0"`))
+}
+
+func TestReleasesHandlerReturnsNotFoundForUnknownPlugin(t *testing.T) {
+	rr := initRouter(t, "/api/v1/plugins/2.0.0/scm-unknown-plugin/releases", "", NewReleasesHandler(testCatalog()))
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestPluginHandlerFiltersForMaxVersion(t *testing.T) {
+	plugin := Plugin{
+		Name: "scm-limited-plugin",
+		Releases: []Release{
+			{Version: "2.0.0", Conditions: Conditions{MinVersion: "2.0.0", MaxVersion: "2.99.0"}},
+			{Version: "1.0.0", Conditions: Conditions{MaxVersion: "1.99.0"}},
+		},
+	}
+	catalog := staticCatalog(NewCatalog([]Plugin{plugin}, nil))
+
+	rr := initRouter(t, "/api/v1/plugins/2.99.0", "", NewPluginHandler(catalog))
+	assert.Contains(t, rr.Body.String(), `"version":"2.0.0"`)
+	assert.Contains(t, rr.Body.String(), `"maxVersion":"2.99.0"`)
+
+	rr = initRouter(t, "/api/v1/plugins/3.0.0", "", NewPluginHandler(catalog))
+	assert.NotContains(t, rr.Body.String(), `"scm-limited-plugin"`)
+}
+
+func TestPluginHandlerComparesMaxVersionWithItsPrecision(t *testing.T) {
+	plugin := Plugin{
+		Name: "scm-limited-plugin",
+		Releases: []Release{
+			{Version: "1.0.0", Conditions: Conditions{MaxVersion: "2.0"}},
+		},
+	}
+	catalog := staticCatalog(NewCatalog([]Plugin{plugin}, nil))
+
+	rr := initRouter(t, "/api/v1/plugins/2.0.1", "", NewPluginHandler(catalog))
+	assert.Contains(t, rr.Body.String(), `"version":"1.0.0"`)
+
+	rr = initRouter(t, "/api/v1/plugins/2.1.0", "", NewPluginHandler(catalog))
+	assert.NotContains(t, rr.Body.String(), `"scm-limited-plugin"`)
+}
+
+func TestPluginHandlerFiltersForVersionRange(t *testing.T) {
+	plugin := Plugin{
+		Name: "scm-limited-plugin",
+		Releases: []Release{
+			{Version: "3.0.0", Conditions: Conditions{Versions: MustParseVersionRange(">=3.0.0")}},
+			{Version: "2.0.0", Conditions: Conditions{Versions: MustParseVersionRange(">=2.0.0 <3.0.0")}},
+		},
+	}
+	catalog := staticCatalog(NewCatalog([]Plugin{plugin}, nil))
+
+	rr := initRouter(t, "/api/v1/plugins/2.1", "", NewPluginHandler(catalog))
+	assert.Contains(t, rr.Body.String(), `"version":"2.0.0"`)
+	assert.Contains(t, rr.Body.String(), `"versions":"\u003e=2.0.0 \u003c3.0.0"`)
+
+	rr = initRouter(t, "/api/v1/plugins/3.1.0", "", NewPluginHandler(catalog))
+	assert.Contains(t, rr.Body.String(), `"version":"3.0.0"`)
+
+	rr = initRouter(t, "/api/v1/plugins/1.9.0", "", NewPluginHandler(catalog))
+	assert.NotContains(t, rr.Body.String(), `"scm-limited-plugin"`)
+}
+
+func TestPluginHandlerFiltersForJavaVersion(t *testing.T) {
+	plugin := Plugin{
+		Name: "scm-java-plugin",
+		Releases: []Release{
+			{Version: "3.0.0", Conditions: Conditions{MinJavaVersion: "17"}},
+			{Version: "2.0.0", Conditions: Conditions{MinJavaVersion: "11", MaxJavaVersion: "16"}},
+			{Version: "1.0.0", Conditions: Conditions{MaxJavaVersion: "1.8"}},
+		},
+	}
+	catalog := staticCatalog(NewCatalog([]Plugin{plugin}, nil))
+
+	rr := initRouter(t, "/api/v1/plugins/2.0.0?jre=17.0.1", "", NewPluginHandler(catalog))
+	assert.Contains(t, rr.Body.String(), `"version":"3.0.0"`)
+	assert.Contains(t, rr.Body.String(), `"minJavaVersion":"17"`)
+
+	rr = initRouter(t, "/api/v1/plugins/2.0.0?jre=11.0.12", "", NewPluginHandler(catalog))
+	assert.Contains(t, rr.Body.String(), `"version":"2.0.0"`)
+	assert.Contains(t, rr.Body.String(), `"maxJavaVersion":"16"`)
+
+	rr = initRouter(t, "/api/v1/plugins/2.0.0?jre=1.8.0_292", "", NewPluginHandler(catalog))
+	assert.Contains(t, rr.Body.String(), `"version":"1.0.0"`)
+}
+
+func TestPluginHandlerIgnoresJavaVersionIfNotRequested(t *testing.T) {
+	plugin := Plugin{
+		Name: "scm-java-plugin",
+		Releases: []Release{
+			{Version: "3.0.0", Conditions: Conditions{MinJavaVersion: "17"}},
+		},
+	}
+	catalog := staticCatalog(NewCatalog([]Plugin{plugin}, nil))
+
+	rr := initRouter(t, "/api/v1/plugins/2.0.0", "", NewPluginHandler(catalog))
+	assert.Contains(t, rr.Body.String(), `"version":"3.0.0"`)
+
+	rr = initRouter(t, "/api/v1/plugins/2.0.0?jre=unknown", "", NewPluginHandler(catalog))
+	assert.Contains(t, rr.Body.String(), `"version":"3.0.0"`)
+}
+
+func TestPluginHandlerFiltersForChannel(t *testing.T) {
+	plugin := Plugin{
+		Name: "scm-channel-plugin",
+		Releases: []Release{
+			{Version: "1.2.0-SNAPSHOT", Channel: ChannelNightly},
+			{Version: "1.1.0", Channel: ChannelBeta},
+			{Version: "1.0.0"},
+		},
+	}
+	catalog := staticCatalog(NewCatalog([]Plugin{plugin}, nil))
+
+	rr := initRouter(t, "/api/v1/plugins/2.0.0", "", NewPluginHandler(catalog))
+	assert.Contains(t, rr.Body.String(), `"version":"1.0.0","date":"","channel":"stable"`)
+
+	rr = initRouter(t, "/api/v1/plugins/2.0.0?channel=beta", "", NewPluginHandler(catalog))
+	assert.Contains(t, rr.Body.String(), `"version":"1.1.0","date":"","channel":"beta"`)
+
+	rr = initRouter(t, "/api/v1/plugins/2.0.0?channel=nightly", "", NewPluginHandler(catalog))
+	assert.Contains(t, rr.Body.String(), `"version":"1.2.0-SNAPSHOT","date":"","channel":"nightly"`)
+}
+
+func TestPluginHandlerRejectsUnknownChannel(t *testing.T) {
+	rr := initRouter(t, "/api/v1/plugins/2.0.0?channel=alpha", "", NewPluginHandler(testCatalog()))
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, "unknown channel alpha\n", rr.Body.String())
+}
+
+func TestPluginHandlerRejectsInvalidVersion(t *testing.T) {
+	rr := initRouter(t, "/api/v1/plugins/latest", "", NewPluginHandler(testCatalog()))
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, "latest is not a valid version\n", rr.Body.String())
+}
+
+func yankedTestPlugin() Plugin {
+	return Plugin{
+		Name: "scm-yanked-plugin",
+		Releases: []Release{
+			{
+				Version:  "1.2.0",
+				Url:      "http://example.com",
+				Checksum: contentChecksum,
+				Yanked:   true,
+				Advisories: []Advisory{
+					{Id: "SCM-2021-2", Severity: SeverityHigh, Description: "broken migration"},
+				},
+			},
+			{
+				Version: "1.1.0",
+				Advisories: []Advisory{
+					{Id: "SCM-2021-1", Severity: SeverityCritical, Description: "remote code execution", FixedIn: "1.1.1"},
+				},
+			},
+			{
+				Version: "1.0.0",
+				Advisories: []Advisory{
+					{Id: "SCM-2021-1", Severity: SeverityCritical, Description: "remote code execution", FixedIn: "1.1.1"},
+				},
+			},
+		},
+	}
+}
+
+func TestPluginHandlerSkipsYankedReleases(t *testing.T) {
+	catalog := staticCatalog(NewCatalog([]Plugin{yankedTestPlugin()}, nil))
+
+	rr := initRouter(t, "/api/v1/plugins/2.0.0", "", NewPluginHandler(catalog))
+
+	assert.Contains(t, rr.Body.String(), `"version":"1.1.0"`)
+	assert.NotContains(t, rr.Body.String(), `"version":"1.2.0"`)
+}
+
+func TestPluginHandlerReturnsAdvisoriesOfAllReleases(t *testing.T) {
+	catalog := staticCatalog(NewCatalog([]Plugin{yankedTestPlugin()}, nil))
+
+	rr := initRouter(t, "/api/v1/plugins/2.0.0", "", NewPluginHandler(catalog))
+
+	assert.Contains(t, rr.Body.String(), `{"id":"SCM-2021-2","severity":"high","description":"broken migration","affectedVersions":["1.2.0"]}`)
+	assert.Contains(t, rr.Body.String(), `{"id":"SCM-2021-1","severity":"critical","description":"remote code execution","fixedIn":"1.1.1","affectedVersions":["1.1.0","1.0.0"]}`)
+}
+
+func TestPluginHandlerExpandsPluginSetsWithCompatibleReleases(t *testing.T) {
+	pluginSet := PluginSet{
+		Id:       "ssh",
+		Versions: MustParseVersionRange(">=2.0.0"),
+		Sequence: 1,
+		Plugins:  []string{"ssh-plugin", "ad-plugin"},
+	}
+	catalog := staticCatalog(NewCatalog(testData, []PluginSet{pluginSet}))
+
+	rr := initRouter(t, "/api/v1/plugins/2.0.1?os=linux&arch=64", "", NewPluginHandler(catalog))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var response struct {
+		Embedded struct {
+			PluginSets []PluginSetResult `json:"plugin-sets"`
+		} `json:"_embedded"`
+	}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Len(t, response.Embedded.PluginSets, 1)
+	result := response.Embedded.PluginSets[0]
+	assert.True(t, result.Complete)
+	assert.Empty(t, result.MissingPlugins)
+	assert.Contains(t, rr.Body.String(), `"_embedded":{"plugins":[{"name":"ssh-plugin"`)
+	assert.Len(t, result.Embedded["plugins"], 2)
+}
+
+func TestPluginHandlerMarksPluginSetsWithoutCompatibleReleasesAsIncomplete(t *testing.T) {
+	pluginSet := PluginSet{
+		Id:       "ssh",
+		Versions: MustParseVersionRange(">=2.0.0"),
+		Sequence: 1,
+		Plugins:  []string{"ssh-plugin", "ad-plugin", "scm-unknown-plugin"},
+	}
+	catalog := staticCatalog(NewCatalog(testData, []PluginSet{pluginSet}))
+
+	rr := initRouter(t, "/api/v1/plugins/2.0.1?os=mac", "", NewPluginHandler(catalog))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"complete":false`)
+	assert.Contains(t, rr.Body.String(), `"missingPlugins":["ssh-plugin","ad-plugin","scm-unknown-plugin"]`)
+	assert.Contains(t, rr.Body.String(), `"_embedded":{"plugins":[]}`)
+}
+
+func translatedCatalog() *CatalogHolder {
+	plugin := testData[1]
+	plugin.CategoryLabel = "Test"
+	plugin.Translations = map[string]PluginTranslation{
+		"de":    {DisplayName: "Active Directory Plugin", CategoryLabel: "Testen"},
+		"pt_BR": {Description: "descrição do plugin"},
+	}
+	return staticCatalog(NewCatalog([]Plugin{plugin}, nil))
+}
+
+func TestPluginHandlerReturnsTranslationForAcceptLanguage(t *testing.T) {
+	header := http.Header{}
+	header.Set("Accept-Language", "de-DE,de;q=0.9")
+
+	rr := initRouterWithHeader(t, "/api/v1/plugins/2.0.1", "", header, NewPluginHandler(translatedCatalog()))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"displayName":"Active Directory Plugin"`)
+	assert.Contains(t, rr.Body.String(), `"description":"description for ad plugin"`)
+	assert.Contains(t, rr.Body.String(), `"category":"test","categoryLabel":"Testen"`)
+}
+
+func TestPluginHandlerReturnsTranslationForLangParameter(t *testing.T) {
+	rr := initRouter(t, "/api/v1/plugins/2.0.1?lang=pt-BR", "", NewPluginHandler(translatedCatalog()))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"displayName":"active directory plugin"`)
+	assert.Contains(t, rr.Body.String(), `"description":"descrição do plugin"`)
+}
+
+func TestPluginHandlerPrefersEnglishTextsOverOtherTranslations(t *testing.T) {
+	header := http.Header{}
+	header.Set("Accept-Language", "en-US,de;q=0.5")
+
+	rr := initRouterWithHeader(t, "/api/v1/plugins/2.0.1", "", header, NewPluginHandler(translatedCatalog()))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"displayName":"active directory plugin"`)
+	assert.Contains(t, rr.Body.String(), `"categoryLabel":"Test"`)
+}
+
+func TestCreateAvatarUrl(t *testing.T) {
+	assert.Equal(t, "", createAvatarUrl(Plugin{}))
+	assert.Equal(t, "https://scm-manager.org/img/mail.png", createAvatarUrl(Plugin{AvatarUrl: "mail.png"}))
+	assert.Equal(t, "https://mirror.example.com/mail.png", createAvatarUrl(Plugin{AvatarUrl: "https://mirror.example.com/mail.png"}))
 }
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SortRelevance = "relevance"
+	SortName      = "name"
+	SortDate      = "date"
+)
+
+var sortOrders = map[string]bool{
+	SortRelevance: true,
+	SortName:      true,
+	SortDate:      true,
+}
+
+// PluginQuery filters, sorts and pages the plugin list. Without a page size the whole list is returned.
+type PluginQuery struct {
+	Terms      []string
+	Categories []string
+	Types      []string
+	Authors    []string
+	Sort       string
+	// Page starts with 1
+	Page     int
+	PageSize int
+}
+
+func (q PluginQuery) IsPaged() bool {
+	return q.PageSize > 0
+}
+
+func extractPluginQuery(values url.Values) (PluginQuery, error) {
+	query := PluginQuery{
+		Terms:      strings.Fields(strings.ToLower(values.Get("q"))),
+		Categories: lowerAll(values["category"]),
+		Types:      lowerAll(values["type"]),
+		Authors:    lowerAll(values["author"]),
+		Sort:       values.Get("sort"),
+		Page:       1,
+	}
+	if query.Sort == "" && len(query.Terms) > 0 {
+		query.Sort = SortRelevance
+	} else if query.Sort != "" && !sortOrders[query.Sort] {
+		return PluginQuery{}, errors.Errorf("unknown sort order %s", query.Sort)
+	}
+
+	var err error
+	if values.Get("pageSize") != "" {
+		query.PageSize, err = strconv.Atoi(values.Get("pageSize"))
+		if err != nil || query.PageSize < 1 {
+			return PluginQuery{}, errors.Errorf("pageSize %s is not a positive number", values.Get("pageSize"))
+		}
+	}
+	if values.Get("page") != "" {
+		query.Page, err = strconv.Atoi(values.Get("page"))
+		if err != nil || query.Page < 1 {
+			return PluginQuery{}, errors.Errorf("page %s is not a positive number", values.Get("page"))
+		}
+	}
+	return query, nil
+}
+
+func lowerAll(values []string) []string {
+	var lowered []string
+	for _, value := range values {
+		lowered = append(lowered, strings.ToLower(value))
+	}
+	return lowered
+}
+
+type rankedPluginResult struct {
+	result PluginResult
+	score  int
+}
+
+// Apply returns the results which match the filters and terms of the query in the order of the query.
+// Without sort order the order of the catalog is kept.
+func (q PluginQuery) Apply(results []PluginResult) []PluginResult {
+	var ranked []rankedPluginResult
+	for _, result := range results {
+		if !q.matchesFilters(result) {
+			continue
+		}
+		score := q.score(result)
+		if len(q.Terms) > 0 && score == 0 {
+			continue
+		}
+		ranked = append(ranked, rankedPluginResult{result: result, score: score})
+	}
+
+	if q.Sort != "" {
+		sort.SliceStable(ranked, func(i, j int) bool {
+			a, b := ranked[i], ranked[j]
+			switch q.Sort {
+			case SortRelevance:
+				if a.score != b.score {
+					return a.score > b.score
+				}
+			case SortDate:
+				dateA, dateB := parseReleaseDate(a.result.Date), parseReleaseDate(b.result.Date)
+				if !dateA.Equal(dateB) {
+					return dateA.After(dateB)
+				}
+			}
+			return a.result.Name < b.result.Name
+		})
+	}
+
+	filtered := []PluginResult{}
+	for _, r := range ranked {
+		filtered = append(filtered, r.result)
+	}
+	return filtered
+}
+
+func (q PluginQuery) matchesFilters(result PluginResult) bool {
+	return matchesAny(q.Categories, result.Category) &&
+		matchesAny(q.Types, result.Type) &&
+		matchesAny(q.Authors, result.Author)
+}
+
+func matchesAny(filter []string, value string) bool {
+	if len(filter) == 0 {
+		return true
+	}
+	value = strings.ToLower(value)
+	for _, candidate := range filter {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+// score ranks matches in the name higher than matches in the other texts.
+// The score is zero if one of the terms does not match at all.
+func (q PluginQuery) score(result PluginResult) int {
+	name := strings.ToLower(result.Name)
+	displayName := strings.ToLower(result.DisplayName)
+	total := 0
+	for _, term := range q.Terms {
+		score := 0
+		if name == term || displayName == term {
+			score += 10
+		}
+		if strings.Contains(name, term) {
+			score += 5
+		}
+		if strings.Contains(displayName, term) {
+			score += 4
+		}
+		if strings.Contains(strings.ToLower(result.Author), term) {
+			score += 2
+		}
+		if strings.Contains(strings.ToLower(result.Description), term) {
+			score++
+		}
+		if score == 0 {
+			return 0
+		}
+		total += score
+	}
+	return total
+}
+
+// parseReleaseDate returns the zero time for dates which are not RFC 3339 timestamps, so that they are sorted last.
+func parseReleaseDate(date string) time.Time {
+	t, err := time.Parse(time.RFC3339, date)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// Paginate returns the results of the requested page.
+func (q PluginQuery) Paginate(results []PluginResult) []PluginResult {
+	if !q.IsPaged() {
+		return results
+	}
+	start := (q.Page - 1) * q.PageSize
+	if start >= len(results) {
+		return []PluginResult{}
+	}
+	end := start + q.PageSize
+	if end > len(results) {
+		end = len(results)
+	}
+	return results[start:end]
+}
+
+func (q PluginQuery) PageTotal(total int) int {
+	return (total + q.PageSize - 1) / q.PageSize
+}
+
+func createPageLinks(generator UrlGenerator, requestUrl url.URL, query PluginQuery, total int) Links {
+	pageUrl := func(page int) Link {
+		parameters := requestUrl.Query()
+		parameters.Set("page", strconv.Itoa(page))
+		return Link{Href: fmt.Sprintf("%v?%v", generator.Url(requestUrl.Path), parameters.Encode())}
+	}
+
+	links := Links{"self": pageUrl(query.Page)}
+	if query.Page > 1 {
+		links["prev"] = pageUrl(query.Page - 1)
+	}
+	if query.Page < query.PageTotal(total) {
+		links["next"] = pageUrl(query.Page + 1)
+	}
+	return links
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+var searchResults = []PluginResult{
+	{Name: "scm-mail-plugin", DisplayName: "Mail", Description: "Sends notifications", Category: "Notification", Type: "SCM", Author: "Cloudogu GmbH", Date: "2021-03-01T10:00:00Z"},
+	{Name: "scm-review-plugin", DisplayName: "Review", Description: "Pull requests with mail notifications", Category: "Workflow", Type: "SCM", Author: "Cloudogu GmbH", Date: "2021-05-01T10:00:00Z"},
+	{Name: "scm-landingpage-plugin", DisplayName: "Landingpage", Description: "Start page", Category: "Workflow", Type: "CLOUDOGU", Author: "Cloudogu GmbH", Date: "1.01.2019"},
+	{Name: "scm-mailer-plugin", DisplayName: "Mailer", Description: "Deprecated", Category: "Notification", Type: "SCM", Author: "Community", Date: "2020-01-01T10:00:00Z"},
+}
+
+func names(results []PluginResult) []string {
+	var n []string
+	for _, result := range results {
+		n = append(n, result.Name)
+	}
+	return n
+}
+
+func mustExtractPluginQuery(t *testing.T, query string) PluginQuery {
+	values, err := url.ParseQuery(query)
+	assert.NoError(t, err)
+	pluginQuery, err := extractPluginQuery(values)
+	assert.NoError(t, err)
+	return pluginQuery
+}
+
+func TestPluginQueryWithoutParametersKeepsAllResultsInOrder(t *testing.T) {
+	query := mustExtractPluginQuery(t, "")
+
+	results := query.Apply(searchResults)
+
+	assert.Equal(t, names(searchResults), names(results))
+	assert.False(t, query.IsPaged())
+	assert.Equal(t, results, query.Paginate(results))
+}
+
+func TestPluginQueryRanksNameMatchesFirst(t *testing.T) {
+	query := mustExtractPluginQuery(t, "q=Mail")
+
+	results := query.Apply(searchResults)
+
+	assert.Equal(t, SortRelevance, query.Sort)
+	assert.Equal(t, []string{"scm-mail-plugin", "scm-mailer-plugin", "scm-review-plugin"}, names(results))
+}
+
+func TestPluginQueryRequiresAllTerms(t *testing.T) {
+	query := mustExtractPluginQuery(t, "q=mail+pull")
+
+	assert.Equal(t, []string{"scm-review-plugin"}, names(query.Apply(searchResults)))
+}
+
+func TestPluginQuerySearchesAuthor(t *testing.T) {
+	query := mustExtractPluginQuery(t, "q=community")
+
+	assert.Equal(t, []string{"scm-mailer-plugin"}, names(query.Apply(searchResults)))
+}
+
+func TestPluginQueryFiltersCaseInsensitive(t *testing.T) {
+	assert.Equal(t, []string{"scm-review-plugin", "scm-landingpage-plugin"}, names(mustExtractPluginQuery(t, "category=workflow").Apply(searchResults)))
+	assert.Equal(t, []string{"scm-landingpage-plugin"}, names(mustExtractPluginQuery(t, "type=cloudogu").Apply(searchResults)))
+	assert.Equal(t, []string{"scm-mailer-plugin"}, names(mustExtractPluginQuery(t, "author=Community").Apply(searchResults)))
+	assert.Equal(t, []string{"scm-mail-plugin", "scm-review-plugin", "scm-mailer-plugin"}, names(mustExtractPluginQuery(t, "category=workflow&category=notification&type=SCM").Apply(searchResults)))
+}
+
+func TestPluginQuerySortsByNameAndDate(t *testing.T) {
+	assert.Equal(t, []string{"scm-landingpage-plugin", "scm-mail-plugin", "scm-mailer-plugin", "scm-review-plugin"}, names(mustExtractPluginQuery(t, "sort=name").Apply(searchResults)))
+	assert.Equal(t, []string{"scm-review-plugin", "scm-mail-plugin", "scm-mailer-plugin", "scm-landingpage-plugin"}, names(mustExtractPluginQuery(t, "sort=date").Apply(searchResults)))
+}
+
+func TestPluginQueryPaginates(t *testing.T) {
+	query := mustExtractPluginQuery(t, "sort=name&pageSize=3&page=2")
+
+	results := query.Paginate(query.Apply(searchResults))
+
+	assert.Equal(t, []string{"scm-review-plugin"}, names(results))
+	assert.Equal(t, 2, query.PageTotal(len(searchResults)))
+	assert.Empty(t, mustExtractPluginQuery(t, "pageSize=3&page=3").Paginate(searchResults))
+}
+
+func TestExtractPluginQueryFailsForInvalidParameters(t *testing.T) {
+	for _, query := range []string{"sort=downloads", "sort=popularity", "pageSize=0", "pageSize=ten", "page=0", "page=-1"} {
+		values, _ := url.ParseQuery(query)
+		_, err := extractPluginQuery(values)
+		assert.Error(t, err, query)
+	}
+}
+
+func TestPluginHandlerReturnsPageWithLinks(t *testing.T) {
+	rr := initRouter(t, "/api/v1/plugins/2.0.1?sort=name&pageSize=1&page=2", "", NewPluginHandler(testCatalog()))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var response Response
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, 2, *response.Page)
+	assert.Equal(t, 2, *response.PageTotal)
+	assert.Len(t, response.Embedded["plugins"], 1)
+	assert.Contains(t, rr.Body.String(), `"name":"ssh-plugin"`)
+	assert.Equal(t, "http:///api/v1/plugins/2.0.1?page=1&pageSize=1&sort=name", response.Links["prev"].Href)
+	assert.Equal(t, "http:///api/v1/plugins/2.0.1?page=2&pageSize=1&sort=name", response.Links["self"].Href)
+	assert.NotContains(t, response.Links, "next")
+	assert.Len(t, response.Embedded["plugin-sets"], 2)
+}
+
+func TestPluginHandlerReturnsEmptyListWithoutMatches(t *testing.T) {
+	rr := initRouter(t, "/api/v1/plugins/2.0.1?q=unknown", "", NewPluginHandler(testCatalog()))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"plugins":[]`)
+	assert.NotContains(t, rr.Body.String(), `"pageTotal"`)
+}
+
+func TestPluginHandlerReturnsEmptyPageWithoutMatches(t *testing.T) {
+	rr := initRouter(t, "/api/v1/plugins/2.0.1?q=unknown&pageSize=10", "", NewPluginHandler(testCatalog()))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"plugins":[]`)
+	assert.Contains(t, rr.Body.String(), `"page":1`)
+	assert.Contains(t, rr.Body.String(), `"pageTotal":0`)
+}
+
+func TestPluginHandlerSearchesPlugins(t *testing.T) {
+	rr := initRouter(t, "/api/v1/plugins/2.0.1?q=directory", "", NewPluginHandler(testCatalog()))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"name":"ad-plugin"`)
+	assert.NotContains(t, rr.Body.String(), `"name":"ssh-plugin"`)
+	assert.NotContains(t, rr.Body.String(), `"_links":{"self"`)
+}
+
+func TestPluginHandlerFailsForUnknownSortOrder(t *testing.T) {
+	rr := initRouter(t, "/api/v1/plugins/2.0.1?sort=downloads", "", NewPluginHandler(testCatalog()))
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "unknown sort order downloads")
+}
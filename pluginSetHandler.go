@@ -0,0 +1,73 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"sort"
+)
+
+// LocalizedPluginSetResult is a plugin set with the description in the language of the request.
+type LocalizedPluginSetResult struct {
+	Id             string          `json:"id"`
+	Versions       VersionRange    `json:"versions"`
+	Sequence       int             `json:"sequence"`
+	Plugins        []string        `json:"plugins"`
+	Language       string          `json:"language"`
+	Description    Description     `json:"description"`
+	Complete       bool            `json:"complete"`
+	MissingPlugins []string        `json:"missingPlugins"`
+	Embedded       EmbeddedObjects `json:"_embedded"`
+	Links          Links           `json:"_links"`
+}
+
+func NewPluginSetHandler(catalogHolder *CatalogHolder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		catalog := catalogHolder.Get()
+
+		requestConditions, err := extractRequestConditions(r)
+		if err != nil {
+			log.Println("invalid request conditions:", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		authenticated := r.Context().Value("subject") != nil
+		urlGenerator := NewUrlGenerator(*r)
+
+		var pluginSetResults []PluginSetResult
+		for _, pluginSet := range catalog.PluginSets {
+			pluginSetResults = appendPluginSetIfOk(pluginSetResults, catalog, pluginSet, requestConditions, urlGenerator, authenticated)
+		}
+		sort.SliceStable(pluginSetResults, func(i, j int) bool {
+			return pluginSetResults[i].Sequence < pluginSetResults[j].Sequence
+		})
+
+		localized := []LocalizedPluginSetResult{}
+		for _, pluginSetResult := range pluginSetResults {
+			localized = append(localized, localizePluginSetResult(pluginSetResult, requestConditions.Languages))
+		}
+
+		writeJson(w, http.StatusOK, Response{Embedded: EmbeddedObjects{"plugin-sets": localized}})
+	}
+}
+
+func localizePluginSetResult(result PluginSetResult, languages []string) LocalizedPluginSetResult {
+	var available []string
+	for language := range result.Descriptions {
+		available = append(available, language)
+	}
+	language := negotiateLanguage(languages, available)
+
+	return LocalizedPluginSetResult{
+		Id:             result.Id,
+		Versions:       result.Versions,
+		Sequence:       result.Sequence,
+		Plugins:        result.Plugins,
+		Language:       language,
+		Description:    result.Descriptions[language],
+		Complete:       result.Complete,
+		MissingPlugins: result.MissingPlugins,
+		Embedded:       result.Embedded,
+		Links:          result.Links,
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+type pluginSetsResponse struct {
+	Embedded struct {
+		PluginSets []LocalizedPluginSetResult `json:"plugin-sets"`
+	} `json:"_embedded"`
+}
+
+func servePluginSets(t *testing.T, url string, header http.Header) pluginSetsResponse {
+	reversed := []PluginSet{testDataPluginSets[1], testDataPluginSets[0]}
+	catalog := staticCatalog(NewCatalog(testData, reversed))
+
+	rr := initRouterWithHeader(t, url, "", header, NewPluginSetHandler(catalog))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var response pluginSetsResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	return response
+}
+
+func TestPluginSetHandlerReturnsPluginSetsOrderedBySequence(t *testing.T) {
+	response := servePluginSets(t, "/api/v1/plugin-sets/2.0.1", http.Header{})
+
+	assert.Len(t, response.Embedded.PluginSets, 2)
+	assert.Equal(t, "plug-and-play", response.Embedded.PluginSets[0].Id)
+	assert.Equal(t, "administration-and-management", response.Embedded.PluginSets[1].Id)
+}
+
+func TestPluginSetHandlerFiltersForVersion(t *testing.T) {
+	response := servePluginSets(t, "/api/v1/plugin-sets/2.0.0", http.Header{})
+
+	assert.Len(t, response.Embedded.PluginSets, 1)
+	assert.Equal(t, "plug-and-play", response.Embedded.PluginSets[0].Id)
+}
+
+func TestPluginSetHandlerNegotiatesLanguage(t *testing.T) {
+	header := http.Header{}
+	header.Set("Accept-Language", "de-DE,de;q=0.9,en;q=0.8")
+
+	response := servePluginSets(t, "/api/v1/plugin-sets/2.0.1", header)
+
+	assert.Equal(t, "de", response.Embedded.PluginSets[0].Language)
+	assert.Equal(t, "Anklicken und loslegen", response.Embedded.PluginSets[0].Description.Name)
+	assert.Equal(t, "en", response.Embedded.PluginSets[1].Language)
+	assert.Equal(t, "Administration and Management", response.Embedded.PluginSets[1].Description.Name)
+}
+
+func TestPluginSetHandlerPrefersLangParameter(t *testing.T) {
+	header := http.Header{}
+	header.Set("Accept-Language", "de")
+
+	response := servePluginSets(t, "/api/v1/plugin-sets/2.0.1?lang=en", header)
+
+	assert.Equal(t, "en", response.Embedded.PluginSets[0].Language)
+	assert.Equal(t, "Plug'n Play", response.Embedded.PluginSets[0].Description.Name)
+}
+
+func TestPluginSetHandlerFallsBackToEnglish(t *testing.T) {
+	response := servePluginSets(t, "/api/v1/plugin-sets/2.0.1?lang=fr", http.Header{})
+
+	assert.Equal(t, "en", response.Embedded.PluginSets[0].Language)
+}
+
+func TestPluginSetHandlerFailsForInvalidVersion(t *testing.T) {
+	rr := initRouter(t, "/api/v1/plugin-sets/latest", "", NewPluginSetHandler(testCatalog()))
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
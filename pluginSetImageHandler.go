@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/gorilla/mux"
+	"net/http"
+	"time"
+)
+
+// images may change with a reload of the catalog, so clients have to revalidate them with the etag after a while
+const imageCacheControl = "public, max-age=3600"
+
+func NewPluginSetImageHandler(catalogHolder *CatalogHolder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+
+		pluginSet, ok := catalogHolder.Get().FindPluginSet(vars["id"])
+		if !ok {
+			writeJsonError(w, http.StatusNotFound, fmt.Sprintf("no plugin set found for id %s", vars["id"]))
+			return
+		}
+
+		image, ok := pluginSet.Images[vars["name"]]
+		if !ok {
+			writeJsonError(w, http.StatusNotFound, fmt.Sprintf("no image %s found for plugin set %s", vars["name"], pluginSet.Id))
+			return
+		}
+
+		w.Header().Set("Content-Type", image.ContentType)
+		w.Header().Set("ETag", image.ETag)
+		w.Header().Set("Cache-Control", imageCacheControl)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		if image.ContentType == imageContentTypes[".svg"] {
+			// svgs which are opened directly must not be able to execute anything, even if the sanitizer missed something
+			w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; img-src data:")
+		}
+		// ServeContent handles If-None-Match with the etag and range requests
+		http.ServeContent(w, r, vars["name"], time.Time{}, bytes.NewReader(image.Data))
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestPluginSetImageHandlerServesImage(t *testing.T) {
+	rr := initRouter(t, "/api/v1/plugin-sets/plug-and-play/images/check", "", NewPluginSetImageHandler(testCatalog()))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "image/svg+xml", rr.Header().Get("Content-Type"))
+	assert.Equal(t, `"check"`, rr.Header().Get("ETag"))
+	assert.Equal(t, imageCacheControl, rr.Header().Get("Cache-Control"))
+	assert.Equal(t, `<svg xmlns="http://www.w3.org/2000/svg"></svg>`, rr.Body.String())
+}
+
+func TestPluginSetImageHandlerReturnsNotModifiedForMatchingETag(t *testing.T) {
+	header := http.Header{}
+	header.Set("If-None-Match", `"check"`)
+
+	rr := initRouterWithHeader(t, "/api/v1/plugin-sets/plug-and-play/images/check", "", header, NewPluginSetImageHandler(testCatalog()))
+
+	assert.Equal(t, http.StatusNotModified, rr.Code)
+	assert.Empty(t, rr.Body.String())
+}
+
+func TestPluginSetImageHandlerReturnsNotFound(t *testing.T) {
+	rr := initRouter(t, "/api/v1/plugin-sets/unknown/images/check", "", NewPluginSetImageHandler(testCatalog()))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	rr = initRouter(t, "/api/v1/plugin-sets/plug-and-play/images/unknown", "", NewPluginSetImageHandler(testCatalog()))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
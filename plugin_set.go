@@ -15,10 +15,19 @@ type Description struct {
 type Descriptions map[string]Description
 
 type PluginSet struct {
-	Id           string            `json:"id"`
-	Versions     VersionRange      `json:"versions"`
-	Sequence     int               `json:"sequence"`
-	Plugins      []string          `json:"plugins"`
-	Descriptions Descriptions      `json:"descriptions"`
-	Images       map[string]string `json:"images"`
+	Id           string           `json:"id"`
+	Versions     VersionRange     `json:"versions"`
+	Sequence     int              `json:"sequence"`
+	Plugins      []string         `json:"plugins"`
+	Descriptions Descriptions     `json:"descriptions"`
+	Images       map[string]Image `json:"-"`
+	// file is the plugins.yml from which the plugin set was read
+	file string
+}
+
+// Image of a plugin set, which is served by the image endpoint.
+type Image struct {
+	ContentType string
+	ETag        string
+	Data        []byte
 }
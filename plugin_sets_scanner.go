@@ -1,34 +1,41 @@
 package main
 
 import (
-	"encoding/base64"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v2"
 	"io/ioutil"
 	"log"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 )
 
-func scanPluginSetsDirectory(directory string) ([]PluginSet, error) {
+// scanPluginSetsDirectory reads all plugin sets of the directory.
+// Broken plugin sets are skipped and reported as problems.
+func scanPluginSetsDirectory(directory string) ([]PluginSet, Problems, error) {
 	var pluginSets []PluginSet
+	var problems Problems
 
 	pluginSetsDirectory, err := ioutil.ReadDir(directory)
 	if err != nil {
-		return nil, errors.Wrapf(err, "could not open plugin sets directory %s", directory)
+		return nil, nil, errors.Wrapf(err, "could not open plugin sets directory %s", directory)
 	}
 
 	for _, pluginSetDirectory := range pluginSetsDirectory {
-		pluginSet, err := readPluginSetDirectory(filepath.Join(directory, pluginSetDirectory.Name()))
-		if err != nil || pluginSet == nil {
-			return pluginSets, err
+		path := filepath.Join(directory, pluginSetDirectory.Name())
+		pluginSet, err := readPluginSetDirectory(path)
+		if err != nil {
+			problems.Add(path, 0, "", err.Error())
+			continue
 		}
 		pluginSets = append(pluginSets, *pluginSet)
 	}
 
-	return pluginSets, nil
+	return pluginSets, problems, nil
 }
 
 func readPluginSetDirectory(pluginSetDirectory string) (*PluginSet, error) {
@@ -55,7 +62,8 @@ func readPluginSetDirectory(pluginSetDirectory string) (*PluginSet, error) {
 		Sequence:     plugins.Sequence,
 		Plugins:      plugins.Plugins,
 		Descriptions: make(map[string]Description),
-		Images:       make(map[string]string),
+		Images:       make(map[string]Image),
+		file:         pluginSetYml,
 	}
 
 	for _, descriptionYml := range descriptionYmls {
@@ -63,7 +71,7 @@ func readPluginSetDirectory(pluginSetDirectory string) (*PluginSet, error) {
 		if err != nil {
 			return nil, err
 		}
-		lang := filepath.Base(descriptionYml)[12:14]
+		lang := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(descriptionYml), "description_"), ".yml")
 		pluginSet.Descriptions[lang] = description
 	}
 
@@ -116,19 +124,73 @@ func readDescriptionsYml(descriptionsYmlPath string) (Description, error) {
 	return description, nil
 }
 
-func appendImages(images map[string]string, pluginSetDirectory string) error {
-	imagePaths, err := filepath.Glob(filepath.Join(pluginSetDirectory, "*.svg"))
-	if err != nil || imagePaths == nil || len(imagePaths) == 0 {
+// imageContentTypes maps the supported image extensions to their content types
+var imageContentTypes = map[string]string{
+	".svg":  "image/svg+xml",
+	".png":  "image/png",
+	".webp": "image/webp",
+}
+
+func appendImages(images map[string]Image, pluginSetDirectory string) error {
+	files, err := ioutil.ReadDir(pluginSetDirectory)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read images at %s", pluginSetDirectory)
+	}
+	found := false
+	for _, file := range files {
+		extension := strings.ToLower(filepath.Ext(file.Name()))
+		contentType, ok := imageContentTypes[extension]
+		if file.IsDir() || !ok {
+			continue
+		}
+		found = true
+		imagePath := filepath.Join(pluginSetDirectory, file.Name())
+		key := strings.TrimSuffix(file.Name(), filepath.Ext(file.Name()))
+		if _, exists := images[key]; exists {
+			return errors.New(fmt.Sprintf("duplicate image %s at %s", key, pluginSetDirectory))
+		}
+		image, err := readImage(imagePath, contentType)
+		if err != nil {
+			return err
+		}
+		images[key] = image
+	}
+	if !found {
 		log.Println("no images found at", pluginSetDirectory)
-		return nil
 	}
-	for _, imagePath := range imagePaths {
-		image, err := ioutil.ReadFile(imagePath)
+	return nil
+}
+
+func readImage(imagePath string, contentType string) (Image, error) {
+	data, err := ioutil.ReadFile(imagePath)
+	if err != nil {
+		return Image{}, errors.Wrapf(err, "failed to read image at %s", imagePath)
+	}
+	if contentType == imageContentTypes[".svg"] {
+		data, err = sanitizeSvg(data)
 		if err != nil {
-			return errors.Wrapf(err, "failed to read image at %s", imagePath)
+			return Image{}, errors.Wrapf(err, "invalid svg at %s", imagePath)
+		}
+	} else if detected := http.DetectContentType(data); detected != contentType {
+		return Image{}, errors.New(fmt.Sprintf("content of %s is %s instead of %s", imagePath, detected, contentType))
+	}
+	checksum := sha256.Sum256(data)
+	return Image{
+		ContentType: contentType,
+		ETag:        `"` + hex.EncodeToString(checksum[:]) + `"`,
+		Data:        data,
+	}, nil
+}
+
+// checkPluginSetMembers reports members of plugin sets which are not part of the catalog.
+func checkPluginSetMembers(problems *Problems, pluginSets []PluginSet, plugins []Plugin) {
+	pluginsByName := createMap(plugins)
+	for _, pluginSet := range pluginSets {
+		data, _ := ioutil.ReadFile(pluginSet.file)
+		for _, name := range pluginSet.Plugins {
+			if _, ok := pluginsByName[name]; !ok {
+				problems.Add(pluginSet.file, findListItemLine(data, name), "plugins", fmt.Sprintf("plugin %s does not exist", name))
+			}
 		}
-		key := strings.TrimSuffix(filepath.Base(imagePath), filepath.Ext(imagePath))
-		images[key] = base64.StdEncoding.EncodeToString(image)
 	}
-	return nil
 }
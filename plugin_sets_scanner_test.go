@@ -2,102 +2,114 @@ package main
 
 import (
 	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"path/filepath"
 	"testing"
 )
 
 func Test_scanPluginSetsDirectory_shouldFailIfDirectoryDoesNotExist(t *testing.T) {
 	configuration := Configuration{DescriptorDirectory: "no/such/folder"}
 
-	_, err := scanPluginSetsDirectory(configuration.DescriptorDirectory)
+	_, _, err := scanPluginSetsDirectory(configuration.DescriptorDirectory)
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "could not open plugin sets directory")
 }
 
-func Test_scanPluginSetsDirectory_shouldFailIfPluginsYmlDoesNotExist(t *testing.T) {
+func Test_scanPluginSetsDirectory_shouldReportProblemIfPluginsYmlDoesNotExist(t *testing.T) {
 	configuration := Configuration{DescriptorDirectory: "resources/test/plugin-sets/plugin-sets-no-plugins-yml"}
 
-	_, err := scanPluginSetsDirectory(configuration.DescriptorDirectory)
+	_, problems, err := scanPluginSetsDirectory(configuration.DescriptorDirectory)
 
-	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "does not contain plugins.yml")
+	assert.NoError(t, err)
+	assert.Len(t, problems, 1)
+	assert.Contains(t, problems[0].Message, "does not contain plugins.yml")
 }
 
-func Test_scanPluginSetsDirectory_shouldFailIfIdIsMissing(t *testing.T) {
+func Test_scanPluginSetsDirectory_shouldReportProblemIfIdIsMissing(t *testing.T) {
 	configuration := Configuration{DescriptorDirectory: "resources/test/plugin-sets/plugin-sets-no-id"}
 
-	_, err := scanPluginSetsDirectory(configuration.DescriptorDirectory)
+	_, problems, err := scanPluginSetsDirectory(configuration.DescriptorDirectory)
 
-	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "id is missing at")
+	assert.NoError(t, err)
+	assert.Len(t, problems, 1)
+	assert.Contains(t, problems[0].Message, "id is missing at")
 }
 
-func Test_scanPluginSetsDirectory_shouldFailIfVersionsIsMissing(t *testing.T) {
+func Test_scanPluginSetsDirectory_shouldReportProblemIfVersionsIsMissing(t *testing.T) {
 	configuration := Configuration{DescriptorDirectory: "resources/test/plugin-sets/plugin-sets-no-versions"}
 
-	_, err := scanPluginSetsDirectory(configuration.DescriptorDirectory)
+	_, problems, err := scanPluginSetsDirectory(configuration.DescriptorDirectory)
 
-	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "versions is missing at")
+	assert.NoError(t, err)
+	assert.Len(t, problems, 1)
+	assert.Contains(t, problems[0].Message, "versions is missing at")
 }
 
-func Test_scanPluginSetsDirectory_shouldFailIfSequenceIsMissing(t *testing.T) {
+func Test_scanPluginSetsDirectory_shouldReportProblemIfSequenceIsMissing(t *testing.T) {
 	configuration := Configuration{DescriptorDirectory: "resources/test/plugin-sets/plugin-sets-no-sequence"}
 
-	_, err := scanPluginSetsDirectory(configuration.DescriptorDirectory)
+	_, problems, err := scanPluginSetsDirectory(configuration.DescriptorDirectory)
 
-	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "sequence is missing or less than one at")
+	assert.NoError(t, err)
+	assert.Len(t, problems, 1)
+	assert.Contains(t, problems[0].Message, "sequence is missing or less than one at")
 }
 
-func Test_scanPluginSetsDirectory_shouldFailIfSequenceIsLessThanOne(t *testing.T) {
+func Test_scanPluginSetsDirectory_shouldReportProblemIfSequenceIsLessThanOne(t *testing.T) {
 	configuration := Configuration{DescriptorDirectory: "resources/test/plugin-sets/plugin-sets-sequence-lt-one"}
 
-	_, err := scanPluginSetsDirectory(configuration.DescriptorDirectory)
+	_, problems, err := scanPluginSetsDirectory(configuration.DescriptorDirectory)
 
-	assert.Error(t, err)
+	assert.NoError(t, err)
+	assert.Len(t, problems, 1)
 }
 
-func Test_scanPluginSetsDirectory_shouldFailIfPluginsAreMissing(t *testing.T) {
+func Test_scanPluginSetsDirectory_shouldReportProblemIfPluginsAreMissing(t *testing.T) {
 	configuration := Configuration{DescriptorDirectory: "resources/test/plugin-sets/plugin-sets-no-plugins"}
 
-	_, err := scanPluginSetsDirectory(configuration.DescriptorDirectory)
+	_, problems, err := scanPluginSetsDirectory(configuration.DescriptorDirectory)
 
-	assert.Error(t, err)
+	assert.NoError(t, err)
+	assert.Len(t, problems, 1)
 }
 
-func Test_scanPluginSetsDirectory_shouldFailIfNoDescriptionYmlExist(t *testing.T) {
+func Test_scanPluginSetsDirectory_shouldReportProblemIfNoDescriptionYmlExist(t *testing.T) {
 	configuration := Configuration{DescriptorDirectory: "resources/test/plugin-sets/plugin-sets-no-description-yml"}
 
-	_, err := scanPluginSetsDirectory(configuration.DescriptorDirectory)
+	_, problems, err := scanPluginSetsDirectory(configuration.DescriptorDirectory)
 
-	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "does not contain any description_*.yml")
+	assert.NoError(t, err)
+	assert.Len(t, problems, 1)
+	assert.Contains(t, problems[0].Message, "does not contain any description_*.yml")
 }
 
-func Test_scanPluginSetsDirectory_shouldFailIfNameIsMissing(t *testing.T) {
+func Test_scanPluginSetsDirectory_shouldReportProblemIfNameIsMissing(t *testing.T) {
 	configuration := Configuration{DescriptorDirectory: "resources/test/plugin-sets/plugin-sets-no-name"}
 
-	_, err := scanPluginSetsDirectory(configuration.DescriptorDirectory)
+	_, problems, err := scanPluginSetsDirectory(configuration.DescriptorDirectory)
 
-	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "name is missing at")
+	assert.NoError(t, err)
+	assert.Len(t, problems, 1)
+	assert.Contains(t, problems[0].Message, "name is missing at")
 }
 
-func Test_scanPluginSetsDirectory_shouldFailIfFeaturesAreMissing(t *testing.T) {
+func Test_scanPluginSetsDirectory_shouldReportProblemIfFeaturesAreMissing(t *testing.T) {
 	configuration := Configuration{DescriptorDirectory: "resources/test/plugin-sets/plugin-sets-no-features"}
 
-	_, err := scanPluginSetsDirectory(configuration.DescriptorDirectory)
+	_, problems, err := scanPluginSetsDirectory(configuration.DescriptorDirectory)
 
-	assert.Error(t, err)
+	assert.NoError(t, err)
+	assert.Len(t, problems, 1)
 }
 
 func Test_scanPluginSetsDirectory_shouldReturnPluginSets(t *testing.T) {
 	configuration := Configuration{DescriptorDirectory: "resources/test/plugin-sets/proper-plugin-sets"}
 
-	pluginSets, err := scanPluginSetsDirectory(configuration.DescriptorDirectory)
+	pluginSets, problems, err := scanPluginSetsDirectory(configuration.DescriptorDirectory)
 
 	assert.NoError(t, err)
+	assert.Empty(t, problems)
 	assert.Len(t, pluginSets, 2)
 
 	pluginSet := findPluginSetById(pluginSets, "plug-and-play")
@@ -114,7 +126,7 @@ func Test_scanPluginSetsDirectory_shouldReturnPluginSets(t *testing.T) {
 func Test_scanPluginSetsDirectory_shouldReadAllDescriptionFiles(t *testing.T) {
 	configuration := Configuration{DescriptorDirectory: "resources/test/plugin-sets/proper-plugin-sets"}
 
-	pluginSets, _ := scanPluginSetsDirectory(configuration.DescriptorDirectory)
+	pluginSets, _, _ := scanPluginSetsDirectory(configuration.DescriptorDirectory)
 	pluginSet := findPluginSetById(pluginSets, "plug-and-play")
 
 	assert.Len(t, pluginSet.Plugins, 9)
@@ -142,12 +154,82 @@ func Test_scanPluginSetsDirectory_shouldReadAllDescriptionFiles(t *testing.T) {
 func Test_scanPluginSetsDirectory_shouldReadAllImageFiles(t *testing.T) {
 	configuration := Configuration{DescriptorDirectory: "resources/test/plugin-sets/proper-plugin-sets"}
 
-	pluginSets, _ := scanPluginSetsDirectory(configuration.DescriptorDirectory)
+	pluginSets, _, _ := scanPluginSetsDirectory(configuration.DescriptorDirectory)
 
 	pluginSet := findPluginSetById(pluginSets, "administration")
 	assert.Len(t, pluginSet.Images, 2)
-	assert.NotEmpty(t, pluginSet.Images["check"])
-	assert.NotEmpty(t, pluginSet.Images["standard"])
+	assert.NotEmpty(t, pluginSet.Images["check"].Data)
+	assert.Equal(t, "image/svg+xml", pluginSet.Images["check"].ContentType)
+	assert.NotEmpty(t, pluginSet.Images["check"].ETag)
+	assert.NotEmpty(t, pluginSet.Images["standard"].Data)
+}
+
+func Test_appendImages_shouldReadPngAndWebp(t *testing.T) {
+	directory := t.TempDir()
+	writeTestFile(t, directory, "logo.png", "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	writeTestFile(t, directory, "banner.webp", "RIFF\x00\x00\x00\x00WEBPVP8 ")
+	writeTestFile(t, directory, "notes.txt", "no image")
+	images := make(map[string]Image)
+
+	err := appendImages(images, directory)
+
+	assert.NoError(t, err)
+	assert.Len(t, images, 2)
+	assert.Equal(t, "image/png", images["logo"].ContentType)
+	assert.Equal(t, "image/webp", images["banner"].ContentType)
+}
+
+func Test_appendImages_shouldSanitizeSvg(t *testing.T) {
+	directory := t.TempDir()
+	writeTestFile(t, directory, "logo.svg", `<svg onload="alert(1)"><script>alert(2)</script></svg>`)
+	images := make(map[string]Image)
+
+	err := appendImages(images, directory)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "<svg></svg>", string(images["logo"].Data))
+}
+
+func Test_appendImages_shouldFailForMismatchingContent(t *testing.T) {
+	directory := t.TempDir()
+	writeTestFile(t, directory, "logo.png", "<svg></svg>")
+
+	err := appendImages(make(map[string]Image), directory)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "instead of image/png")
+}
+
+func Test_appendImages_shouldFailForDuplicateNames(t *testing.T) {
+	directory := t.TempDir()
+	writeTestFile(t, directory, "logo.svg", "<svg></svg>")
+	writeTestFile(t, directory, "logo.png", "\x89PNG\r\n\x1a\n")
+
+	err := appendImages(make(map[string]Image), directory)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "duplicate image logo")
+}
+
+func writeTestFile(t *testing.T, directory string, name string, content string) {
+	err := ioutil.WriteFile(filepath.Join(directory, name), []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_readPluginSetDirectory_shouldReadLanguagesWithRegionAndScript(t *testing.T) {
+	directory := t.TempDir()
+	writeTestFile(t, directory, "plugins.yml", "id: test\nversions: \">=2.0.0\"\nsequence: 1\nplugins:\n  - scm-mail-plugin\n")
+	writeTestFile(t, directory, "description_pt_BR.yml", "name: Teste\nfeatures:\n  - Recurso\n")
+	writeTestFile(t, directory, "description_zh-Hant.yml", "name: 測試\nfeatures:\n  - 功能\n")
+
+	pluginSet, err := readPluginSetDirectory(directory)
+
+	assert.NoError(t, err)
+	assert.Len(t, pluginSet.Descriptions, 2)
+	assert.Equal(t, "Teste", pluginSet.Descriptions["pt_BR"].Name)
+	assert.Equal(t, "測試", pluginSet.Descriptions["zh-Hant"].Name)
 }
 
 func Test_readPluginsYml_shouldFailIfFileDoesNotExist(t *testing.T) {
@@ -183,9 +265,9 @@ func Test_readDescriptionsYml_shouldFailIfYmlIsBroken(t *testing.T) {
 }
 
 func Test_appendImages_shouldReturnNilIfNoImageExists(t *testing.T) {
-	var images map[string]string
+	images := make(map[string]Image)
 
-	err := appendImages(images, "resources/test/plugin-sets/plugin-set-no-images/plugin-set")
+	err := appendImages(images, "resources/test/plugin-sets/plugin-sets-no-images/plugin-set")
 
 	assert.NoError(t, err)
 	assert.Empty(t, images)
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Problem describes an error in a descriptor file.
+type Problem struct {
+	File    string `json:"file"`
+	Line    int    `json:"line,omitempty"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+func (p Problem) String() string {
+	location := p.File
+	if p.Line > 0 {
+		location += ":" + strconv.Itoa(p.Line)
+	}
+	if p.Field != "" {
+		return fmt.Sprintf("%s: %s: %s", location, p.Field, p.Message)
+	}
+	return fmt.Sprintf("%s: %s", location, p.Message)
+}
+
+type Problems []Problem
+
+func (p Problems) Error() string {
+	messages := make([]string, len(p))
+	for i, problem := range p {
+		messages[i] = problem.String()
+	}
+	return fmt.Sprintf("found %d problems: %s", len(p), strings.Join(messages, "; "))
+}
+
+func (p *Problems) Add(file string, line int, field string, message string) {
+	*p = append(*p, Problem{File: file, Line: line, Field: field, Message: message})
+}
+
+// ForFile returns the problems of the given file.
+func (p Problems) ForFile(file string) Problems {
+	var problems Problems
+	for _, problem := range p {
+		if problem.File == file {
+			problems = append(problems, problem)
+		}
+	}
+	return problems
+}
+
+var yamlErrorLine = regexp.MustCompile(`line (\d+): (.*)`)
+
+// AddYamlError splits the given yaml error into one problem per reported line.
+func (p *Problems) AddYamlError(file string, err error) {
+	err = errors.Cause(err)
+	messages := []string{err.Error()}
+	if typeError, ok := err.(*yaml.TypeError); ok {
+		messages = typeError.Errors
+	}
+	for _, message := range messages {
+		message = strings.TrimPrefix(message, "yaml: ")
+		match := yamlErrorLine.FindStringSubmatch(message)
+		if match == nil {
+			p.Add(file, 0, "", message)
+			continue
+		}
+		line, _ := strconv.Atoi(match[1])
+		p.Add(file, line, "", match[2])
+	}
+}
+
+// findLine returns the first line number on which the given key is defined or zero, if the key could not be found.
+// Keys of list items are found as well.
+func findLine(data []byte, key string) int {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimPrefix(strings.TrimSpace(scanner.Text()), "- ")
+		if strings.HasPrefix(text, key+":") {
+			return line
+		}
+	}
+	return 0
+}
+
+// findKeyValueLine returns the first line number on which the given key is defined with the given value or zero,
+// if the key could not be found. Keys of list items are found as well.
+func findKeyValueLine(data []byte, key string, value string) int {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimPrefix(strings.TrimSpace(scanner.Text()), "- ")
+		if !strings.HasPrefix(text, key+":") {
+			continue
+		}
+		if strings.Trim(strings.TrimSpace(strings.TrimPrefix(text, key+":")), `"'`) == value {
+			return line
+		}
+	}
+	return 0
+}
+
+// findListItemLine returns the first line number on which the given value is defined as list item or zero,
+// if the value could not be found.
+func findListItemLine(data []byte, value string) int {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	line := 0
+	for scanner.Scan() {
+		line++
+		item := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(item, "-") {
+			continue
+		}
+		item = strings.Trim(strings.TrimSpace(strings.TrimPrefix(item, "-")), `"'`)
+		if item == value {
+			return line
+		}
+	}
+	return 0
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"github.com/gorilla/mux"
+	"gopkg.in/yaml.v2"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	maxUploadSize       = 1 << 30
+	maxUploadMemorySize = 32 << 20
+)
+
+type PublishResult struct {
+	Release  ReleaseDetail `json:"release"`
+	Problems Problems      `json:"problems,omitempty"`
+}
+
+// NewPublishHandler creates a handler which adds a release to the descriptor directory and reloads the catalog.
+// The release descriptor is passed as yaml or json body or as "release" field of a multipart form, which may
+// contain the plugin artifact as "artifact" file. Uploaded artifacts are stored next to the descriptor.
+func NewPublishHandler(catalog *CatalogHolder, descriptorDirectory string) http.HandlerFunc {
+	publisher := publisher{catalog: catalog, descriptorDirectory: descriptorDirectory}
+	return publisher.handle
+}
+
+type publisher struct {
+	catalog             *CatalogHolder
+	descriptorDirectory string
+	mutex               sync.Mutex
+}
+
+func (p *publisher) handle(w http.ResponseWriter, r *http.Request) {
+	pluginName := mux.Vars(r)["name"]
+	plugin, ok := p.catalog.Get().FindPlugin(pluginName)
+	if !ok {
+		writeJsonError(w, http.StatusNotFound, fmt.Sprintf("no plugin found for name %s", pluginName))
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	data, artifact, err := readPublishRequest(r)
+	if err != nil {
+		writeJsonError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if artifact != nil {
+		defer func() {
+			_ = artifact.Close()
+		}()
+	}
+
+	var release Release
+	if err := yaml.UnmarshalStrict(data, &release); err != nil {
+		var problems Problems
+		problems.AddYamlError("release", err)
+		writeJson(w, http.StatusBadRequest, PublishResult{Problems: problems})
+		return
+	}
+	if release.Plugin == "" {
+		release.Plugin = pluginName
+	}
+
+	// the release is validated before anything is written, because the version is used in file names
+	var problems Problems
+	checkRelease(&problems, "release", data, pluginName, release)
+	checkAdvisories(&problems, "release", data, release)
+	if artifact != nil {
+		// the url and the checksum are computed from the uploaded artifact
+		problems = withoutFields(problems, "url", "checksum")
+	}
+	if len(problems) > 0 {
+		writeJson(w, http.StatusBadRequest, PublishResult{Problems: problems})
+		return
+	}
+
+	// the mutex prevents concurrent publishing of the same version
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	releaseFile, err := joinWithin(p.descriptorDirectory, pluginName, "releases", strings.ReplaceAll(release.Version, ".", "-")+".yml")
+	if err != nil {
+		writeJsonError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	_, found := plugin.FindRelease(release.Version)
+	if fileFound, _ := exists(releaseFile); found || fileFound {
+		writeJsonError(w, http.StatusConflict, fmt.Sprintf("release %s of plugin %s already exists", release.Version, pluginName))
+		return
+	}
+
+	artifactPath := ""
+	if artifact != nil {
+		artifactPath, err = joinWithin(p.descriptorDirectory, pluginName, "artifacts", pluginName+"-"+release.Version+".smp")
+		if err != nil {
+			writeJsonError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		checksum, err := writeArtifact(artifactPath, artifact, release.Checksum)
+		if err == errChecksumMismatch {
+			writeJsonError(w, http.StatusBadRequest, fmt.Sprintf("checksum of artifact is %s, but release declares %s", checksum, release.Checksum))
+			return
+		}
+		if err != nil {
+			log.Println("could not store artifact of plugin", pluginName, err)
+			writeJsonError(w, http.StatusInternalServerError, "could not store artifact")
+			return
+		}
+		absolutePath, err := filepath.Abs(artifactPath)
+		if err != nil {
+			absolutePath = artifactPath
+		}
+		release.Checksum = checksum
+		release.Url = "file://" + filepath.ToSlash(absolutePath)
+	}
+
+	if err := writeYml(releaseFile, release); err != nil {
+		log.Println("could not write release of plugin", pluginName, err)
+		removeFile(artifactPath)
+		writeJsonError(w, http.StatusInternalServerError, "could not write release")
+		return
+	}
+
+	diff, err := p.catalog.Reload()
+	if err != nil {
+		log.Println("failed to reload catalog after publishing release", release.Version, "of plugin", pluginName, err)
+		removeFile(releaseFile)
+		removeFile(artifactPath)
+		writeJsonError(w, http.StatusInternalServerError, "failed to reload catalog: "+err.Error())
+		return
+	}
+	log.Println("published release", release.Version, "of plugin", pluginName+":", diff)
+
+	plugin, _ = p.catalog.Get().FindPlugin(pluginName)
+	if _, found := plugin.FindRelease(release.Version); !found {
+		// the release was skipped by the scanner, because of problems which could not be detected before
+		removeFile(releaseFile)
+		removeFile(artifactPath)
+		_, _ = p.catalog.Reload()
+		writeJson(w, http.StatusBadRequest, PublishResult{Problems: p.catalog.Get().Problems.ForFile(releaseFile)})
+		return
+	}
+	writeJson(w, http.StatusCreated, PublishResult{
+		Release:  createReleaseDetail(plugin, release, NewUrlGenerator(*r), true),
+		Problems: p.catalog.Get().Problems.ForFile(releaseFile),
+	})
+}
+
+// readPublishRequest returns the release descriptor and the artifact, which is nil if no artifact was uploaded.
+func readPublishRequest(r *http.Request) ([]byte, io.ReadCloser, error) {
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		data, err := ioutil.ReadAll(r.Body)
+		return data, nil, err
+	}
+
+	if err := r.ParseMultipartForm(maxUploadMemorySize); err != nil {
+		return nil, nil, err
+	}
+	data := []byte(r.FormValue("release"))
+	if len(data) == 0 {
+		return nil, nil, fmt.Errorf("form field release is required")
+	}
+	artifact, _, err := r.FormFile("artifact")
+	if err == http.ErrMissingFile {
+		return data, nil, nil
+	}
+	return data, artifact, err
+}
+
+// withoutFields returns the problems which do not belong to one of the fields.
+func withoutFields(problems Problems, fields ...string) Problems {
+	var filtered Problems
+	for _, problem := range problems {
+		skip := false
+		for _, field := range fields {
+			if problem.Field == field {
+				skip = true
+			}
+		}
+		if !skip {
+			filtered = append(filtered, problem)
+		}
+	}
+	return filtered
+}
+
+func removeFile(path string) {
+	if path == "" {
+		return
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		log.Println("could not remove", path, err)
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func createPublishCatalog(t *testing.T) (*CatalogHolder, string) {
+	directory := t.TempDir()
+	writePluginYml(t, directory, "scm-mail-plugin")
+	holder, err := NewCatalogHolder(NewDirectoryCatalogLoader(Configuration{
+		DescriptorDirectory: directory,
+		PluginSetsDirectory: "resources/test/plugin-sets/proper-plugin-sets",
+	}))
+	assert.NoError(t, err)
+	return holder, directory
+}
+
+func servePublishRequest(t *testing.T, catalog *CatalogHolder, directory string, plugin string, contentType string, body io.Reader) *httptest.ResponseRecorder {
+	req, err := http.NewRequest(http.MethodPost, "/api/v1/admin/plugins/"+plugin+"/releases", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	rr := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.Handle("/api/v1/admin/plugins/{name}/releases", NewPublishHandler(catalog, directory))
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestPublishReleaseWithMetadata(t *testing.T) {
+	catalog, directory := createPublishCatalog(t)
+	body := `{"tag": "2.0.0", "url": "https://download.scm-manager.org/scm-mail-plugin-2.0.0.smp", "checksum": "` + contentChecksum + `"}`
+
+	rr := servePublishRequest(t, catalog, directory, "scm-mail-plugin", "application/json", strings.NewReader(body))
+
+	assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	assert.Contains(t, rr.Body.String(), `"version":"2.0.0"`)
+	plugin, _ := catalog.Get().FindPlugin("scm-mail-plugin")
+	release, ok := plugin.FindRelease("2.0.0")
+	assert.True(t, ok)
+	assert.Equal(t, "scm-mail-plugin", release.Plugin)
+	assert.FileExists(t, filepath.Join(directory, "scm-mail-plugin", "releases", "2-0-0.yml"))
+}
+
+func TestPublishReleaseWithArtifact(t *testing.T) {
+	catalog, directory := createPublishCatalog(t)
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	assert.NoError(t, writer.WriteField("release", "tag: 2.0.0\nconditions:\n  minVersion: 2.0.0\n"))
+	part, err := writer.CreateFormFile("artifact", "scm-mail-plugin.smp")
+	assert.NoError(t, err)
+	_, _ = part.Write([]byte("content"))
+	assert.NoError(t, writer.Close())
+
+	rr := servePublishRequest(t, catalog, directory, "scm-mail-plugin", writer.FormDataContentType(), &body)
+
+	assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var result PublishResult
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &result))
+	assert.Equal(t, contentChecksum, result.Release.Checksum)
+
+	plugin, _ := catalog.Get().FindPlugin("scm-mail-plugin")
+	release, ok := plugin.FindRelease("2.0.0")
+	assert.True(t, ok)
+	store, err := NewArtifactStore(Configuration{DescriptorDirectory: directory})
+	assert.NoError(t, err)
+	assert.Equal(t, "content", readArtifact(t, store, release.Url))
+}
+
+func TestPublishRejectsArtifactWithWrongChecksum(t *testing.T) {
+	catalog, directory := createPublishCatalog(t)
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	assert.NoError(t, writer.WriteField("release", "tag: 2.0.0\nchecksum: "+checksumOf("other")+"\n"))
+	part, err := writer.CreateFormFile("artifact", "scm-mail-plugin.smp")
+	assert.NoError(t, err)
+	_, _ = part.Write([]byte("content"))
+	assert.NoError(t, writer.Close())
+
+	rr := servePublishRequest(t, catalog, directory, "scm-mail-plugin", writer.FormDataContentType(), &body)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	_, err = os.Stat(filepath.Join(directory, "scm-mail-plugin", "artifacts", "scm-mail-plugin-2.0.0.smp"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestPublishRejectsInvalidRelease(t *testing.T) {
+	catalog, directory := createPublishCatalog(t)
+
+	rr := servePublishRequest(t, catalog, directory, "scm-mail-plugin", "application/x-yaml", strings.NewReader("tag: next\n"))
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "next is not a valid version")
+	assert.Contains(t, rr.Body.String(), "url is missing")
+	_, err := os.Stat(filepath.Join(directory, "scm-mail-plugin", "releases", "next.yml"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestPublishRejectsUnknownKeys(t *testing.T) {
+	catalog, directory := createPublishCatalog(t)
+
+	rr := servePublishRequest(t, catalog, directory, "scm-mail-plugin", "application/x-yaml", strings.NewReader("tag: 2.0.0\nversion: 2.0.0\n"))
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "field version not found")
+}
+
+func TestPublishRejectsExistingRelease(t *testing.T) {
+	catalog, directory := createPublishCatalog(t)
+	body := "tag: 2.0.0\nurl: https://download.scm-manager.org/a.smp\nchecksum: " + contentChecksum + "\n"
+	rr := servePublishRequest(t, catalog, directory, "scm-mail-plugin", "application/x-yaml", strings.NewReader(body))
+	assert.Equal(t, http.StatusCreated, rr.Code)
+
+	rr = servePublishRequest(t, catalog, directory, "scm-mail-plugin", "application/x-yaml", strings.NewReader(body))
+
+	assert.Equal(t, http.StatusConflict, rr.Code)
+}
+
+func TestPublishRejectsUnknownPlugin(t *testing.T) {
+	catalog, directory := createPublishCatalog(t)
+
+	rr := servePublishRequest(t, catalog, directory, "scm-unknown-plugin", "application/x-yaml", strings.NewReader("tag: 1.0.0\n"))
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestPublishRejectsVersionsWhichLeaveTheDescriptorDirectory(t *testing.T) {
+	catalog, directory := createPublishCatalog(t)
+	victim := filepath.Join(t.TempDir(), "victim.smp")
+	assert.NoError(t, ioutil.WriteFile(victim, []byte("keep"), 0644))
+	version := "1.0.0/" + strings.Repeat("../", 8) + strings.TrimPrefix(strings.TrimSuffix(victim, ".smp"), "/")
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	assert.NoError(t, writer.WriteField("release", "tag: "+version+"\n"))
+	part, err := writer.CreateFormFile("artifact", "scm-mail-plugin.smp")
+	assert.NoError(t, err)
+	_, _ = part.Write([]byte("content"))
+	assert.NoError(t, writer.Close())
+
+	rr := servePublishRequest(t, catalog, directory, "scm-mail-plugin", writer.FormDataContentType(), &body)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "is not a valid version")
+	content, err := ioutil.ReadFile(victim)
+	assert.NoError(t, err)
+	assert.Equal(t, "keep", string(content))
+	_, err = os.Stat(filepath.Join(directory, "scm-mail-plugin", "artifacts"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestPublishValidatesReleaseBeforeStoringArtifact(t *testing.T) {
+	catalog, directory := createPublishCatalog(t)
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	assert.NoError(t, writer.WriteField("release", "tag: 2.0.0\nchannel: alpha\n"))
+	part, err := writer.CreateFormFile("artifact", "scm-mail-plugin.smp")
+	assert.NoError(t, err)
+	_, _ = part.Write([]byte("content"))
+	assert.NoError(t, writer.Close())
+
+	rr := servePublishRequest(t, catalog, directory, "scm-mail-plugin", writer.FormDataContentType(), &body)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "alpha is not a valid channel")
+	_, err = os.Stat(filepath.Join(directory, "scm-mail-plugin", "artifacts"))
+	assert.True(t, os.IsNotExist(err))
+}
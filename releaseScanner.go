@@ -1,6 +1,8 @@
 package main
 
 import (
+	"fmt"
+	"github.com/hashicorp/go-version"
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v2"
 	"io/ioutil"
@@ -9,60 +11,134 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 )
 
-func scanDirectory(directory string) ([]Plugin, error) {
+func scanDirectory(directory string) ([]Plugin, Problems, error) {
 
 	var plugins []Plugin
+	var problems Problems
 
 	pluginDirectories, err := ioutil.ReadDir(directory)
 
 	if err != nil {
-		return nil, errors.Wrap(err, "could not open plugin directory "+directory)
+		return nil, nil, errors.Wrap(err, "could not open plugin directory "+directory)
 	}
 
 	for _, pluginDirectory := range pluginDirectories {
-		plugin := readPluginDirectory(filepath.Join(directory, pluginDirectory.Name()))
+		plugin := readPluginDirectory(filepath.Join(directory, pluginDirectory.Name()), &problems)
 		if plugin != nil {
 			plugins = append(plugins, *plugin)
 		}
 	}
 
-	return plugins, nil
+	checkDependencyVersions(&problems, plugins)
+
+	return plugins, problems, nil
+}
+
+// checkDependencyVersions reports dependencies with a version range, which is not satisfied by any release.
+func checkDependencyVersions(problems *Problems, plugins []Plugin) {
+	pluginsByName := createMap(plugins)
+	for _, plugin := range plugins {
+		for _, release := range plugin.Releases {
+			data, _ := ioutil.ReadFile(release.file)
+			for _, dependency := range release.AllDependencies() {
+				if dependency.HasVersions() && !isSatisfiable(pluginsByName, dependency) {
+					problems.Add(release.file, findKeyValueLine(data, "name", dependency.Name), "dependencies", fmt.Sprintf("no release of %s satisfies %s", dependency.Name, dependency.Versions.Value))
+				}
+			}
+		}
+	}
 }
 
-func readPluginDirectory(pluginDirectory string) *Plugin {
+func isSatisfiable(plugins map[string]Plugin, dependency Dependency) bool {
+	for _, release := range plugins[dependency.Name].Releases {
+		if dependency.IsSatisfiedBy(release.Version) {
+			return true
+		}
+	}
+	return false
+}
+
+func readPluginDirectory(pluginDirectory string, problems *Problems) *Plugin {
 	pluginYml := filepath.Join(pluginDirectory, "plugin.yml")
 	if _, err := os.Stat(pluginYml); os.IsNotExist(err) {
 		log.Printf("directory %s does not contain a plugin.yml", pluginDirectory)
 		return nil
 	}
 	plugin, err := readPluginYml(pluginYml)
-	if err == nil {
-		releases := readReleases(filepath.Join(pluginDirectory, "releases"))
-		plugin.Releases = releases
-		return &plugin
-	} else {
-		log.Fatalln("could not read plugin directory", pluginDirectory)
+	if err != nil {
+		problems.AddYamlError(pluginYml, err)
 		return nil
 	}
+	if plugin.Name == "" {
+		problems.Add(pluginYml, 0, "name", "name is missing")
+		return nil
+	}
+	readTranslations(pluginDirectory, &plugin, problems)
+	plugin.Releases = readReleases(filepath.Join(pluginDirectory, "releases"), plugin.Name, problems)
+	return &plugin
 }
 
-func readReleases(releaseDirectory string) []Release {
+func readTranslations(pluginDirectory string, plugin *Plugin, problems *Problems) {
+	translationYmls, _ := filepath.Glob(filepath.Join(pluginDirectory, "plugin_*.yml"))
+	for _, translationYml := range translationYmls {
+		language := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(translationYml), "plugin_"), ".yml")
+		if _, ok := plugin.Translations[language]; ok {
+			problems.Add(translationYml, 0, "", fmt.Sprintf("translation for %s is already defined in plugin.yml", language))
+			continue
+		}
+		data, err := ioutil.ReadFile(translationYml)
+		if err != nil {
+			problems.AddYamlError(translationYml, err)
+			continue
+		}
+		var translation PluginTranslation
+		if err = yaml.Unmarshal(data, &translation); err != nil {
+			problems.AddYamlError(translationYml, err)
+			continue
+		}
+		if plugin.Translations == nil {
+			plugin.Translations = make(map[string]PluginTranslation)
+		}
+		plugin.Translations[language] = translation
+	}
+}
+
+func readReleases(releaseDirectory string, pluginName string, problems *Problems) []Release {
 	var releases []Release
 	releaseFiles, err := ioutil.ReadDir(releaseDirectory)
 	if err != nil {
 		return releases
 	}
 
+	tags := make(map[string]string)
 	for _, releaseFile := range releaseFiles {
 		if strings.HasSuffix(releaseFile.Name(), ".yaml") || strings.HasSuffix(releaseFile.Name(), ".yml") {
 			releaseFilePath := filepath.Join(releaseDirectory, releaseFile.Name())
 			log.Println("reading release file", releaseFilePath)
-			release, err := readRelease(releaseFilePath)
+			release, data, err := readRelease(releaseFilePath)
 			if err != nil {
-				log.Fatalln("could not read release file", releaseFilePath)
+				problems.AddYamlError(releaseFilePath, err)
+				continue
+			}
+
+			problemCount := len(*problems)
+			checkRelease(problems, releaseFilePath, data, pluginName, release)
+			if other, ok := tags[release.Version]; ok {
+				problems.Add(releaseFilePath, findLine(data, "tag"), "tag", fmt.Sprintf("duplicate tag %s, already defined in %s", release.Version, other))
 			}
+			if len(*problems) > problemCount {
+				log.Println("skipping broken release file", releaseFilePath)
+				continue
+			}
+
+			// broken advisories are reported, but do not hide the release or its yanked state
+			checkAdvisories(problems, releaseFilePath, data, release)
+
+			tags[release.Version] = releaseFilePath
+			release.file = releaseFilePath
 			releases = append(releases, release)
 		}
 	}
@@ -72,13 +148,88 @@ func readReleases(releaseDirectory string) []Release {
 	return releases
 }
 
+func checkRelease(problems *Problems, file string, data []byte, pluginName string, release Release) {
+	if release.Plugin != "" && release.Plugin != pluginName {
+		problems.Add(file, findLine(data, "plugin"), "plugin", fmt.Sprintf("release belongs to %s, but is stored in the directory of %s", release.Plugin, pluginName))
+	}
+	if release.Version == "" {
+		problems.Add(file, findLine(data, "tag"), "tag", "tag is missing")
+	} else if _, err := version.NewVersion(release.Version); err != nil {
+		problems.Add(file, findLine(data, "tag"), "tag", fmt.Sprintf("%s is not a valid version", release.Version))
+	}
+	if release.Url == "" {
+		problems.Add(file, findLine(data, "url"), "url", "url is missing")
+	}
+	if release.Checksum == "" {
+		problems.Add(file, findLine(data, "checksum"), "checksum", "checksum is missing")
+	}
+	if release.Rollout != nil {
+		checkRollout(problems, file, data, *release.Rollout)
+	}
+	if !IsValidChannel(release.GetChannel()) {
+		problems.Add(file, findLine(data, "channel"), "channel", fmt.Sprintf("%s is not a valid channel", release.Channel))
+	}
+	if release.Conditions.MinVersion != "" {
+		if _, err := version.NewVersion(release.Conditions.MinVersion); err != nil {
+			problems.Add(file, findLine(data, "minVersion"), "conditions.minVersion", fmt.Sprintf("%s is not a valid version", release.Conditions.MinVersion))
+		}
+	}
+	if release.Conditions.MaxVersion != "" {
+		if _, err := version.NewVersion(release.Conditions.MaxVersion); err != nil {
+			problems.Add(file, findLine(data, "maxVersion"), "conditions.maxVersion", fmt.Sprintf("%s is not a valid version", release.Conditions.MaxVersion))
+		}
+	}
+	if release.Conditions.MinJavaVersion != "" {
+		if _, err := parseJavaVersion(release.Conditions.MinJavaVersion); err != nil {
+			problems.Add(file, findLine(data, "minJavaVersion"), "conditions.minJavaVersion", fmt.Sprintf("%s is not a valid java version", release.Conditions.MinJavaVersion))
+		}
+	}
+	if release.Conditions.MaxJavaVersion != "" {
+		if _, err := parseJavaVersion(release.Conditions.MaxJavaVersion); err != nil {
+			problems.Add(file, findLine(data, "maxJavaVersion"), "conditions.maxJavaVersion", fmt.Sprintf("%s is not a valid java version", release.Conditions.MaxJavaVersion))
+		}
+	}
+	for _, dependency := range release.AllDependencies() {
+		if dependency.Name == "" {
+			problems.Add(file, findLine(data, "name"), "dependencies", "name of dependency is missing")
+		}
+	}
+}
+
+func checkRollout(problems *Problems, file string, data []byte, rollout Rollout) {
+	if rollout.Percentage < 0 || rollout.Percentage > 100 {
+		problems.Add(file, findLine(data, "percentage"), "rollout.percentage", fmt.Sprintf("%d is not between 0 and 100", rollout.Percentage))
+	}
+	if rollout.Start != "" {
+		if _, err := time.Parse(time.RFC3339, rollout.Start); err != nil {
+			problems.Add(file, findLine(data, "start"), "rollout.start", fmt.Sprintf("%s is not a valid RFC 3339 timestamp", rollout.Start))
+		}
+	}
+}
+
+func checkAdvisories(problems *Problems, file string, data []byte, release Release) {
+	for _, advisory := range release.Advisories {
+		checkAdvisory(problems, file, data, advisory)
+	}
+}
+
+func checkAdvisory(problems *Problems, file string, data []byte, advisory Advisory) {
+	if !severities[advisory.Severity] {
+		problems.Add(file, findLine(data, "severity"), "advisories.severity", fmt.Sprintf("%s is not a valid severity", advisory.Severity))
+	}
+	if advisory.FixedIn != "" {
+		if _, err := version.NewVersion(advisory.FixedIn); err != nil {
+			problems.Add(file, findLine(data, "fixedIn"), "advisories.fixedIn", fmt.Sprintf("%s is not a valid version", advisory.FixedIn))
+		}
+	}
+}
+
 func readPluginYml(pluginYmlFileName string) (Plugin, error) {
 	log.Println("reading plugin file", pluginYmlFileName)
 
 	pluginYml, err := ioutil.ReadFile(pluginYmlFileName)
 	if err != nil {
-		log.Println("failed to read plugin.yml at", pluginYmlFileName)
-		return Plugin{}, nil
+		return Plugin{}, errors.Wrapf(err, "failed to read plugin.yml at %s", pluginYmlFileName)
 	}
 
 	var plugin Plugin
@@ -89,12 +240,15 @@ func readPluginYml(pluginYmlFileName string) (Plugin, error) {
 	return plugin, nil
 }
 
-func readRelease(releaseFileName string) (Release, error) {
+func readRelease(releaseFileName string) (Release, []byte, error) {
 	releaseYaml, err := ioutil.ReadFile(releaseFileName)
 	if err != nil {
-		return Release{}, errors.Wrap(err, "could not read release file "+releaseFileName)
+		return Release{}, nil, errors.Wrap(err, "could not read release file "+releaseFileName)
 	}
 	var release Release
 	err = yaml.Unmarshal(releaseYaml, &release)
-	return release, nil
+	if err != nil {
+		return Release{}, releaseYaml, errors.Wrap(err, "could not unmarshal release file "+releaseFileName)
+	}
+	return release, releaseYaml, nil
 }
@@ -2,13 +2,16 @@ package main
 
 import (
 	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
 func TestFailureForNotExistingPluginsFolder(t *testing.T) {
 	configuration := Configuration{DescriptorDirectory: "no/such/folder"}
 
-	_, err := scanDirectory(configuration.DescriptorDirectory)
+	_, _, err := scanDirectory(configuration.DescriptorDirectory)
 
 	assert.Error(t, err, "expected error missing", err)
 }
@@ -16,16 +19,17 @@ func TestFailureForNotExistingPluginsFolder(t *testing.T) {
 func TestIfReleaseFilesAreRead(t *testing.T) {
 	configuration := Configuration{DescriptorDirectory: "resources/test/plugins"}
 
-	plugins, err := scanDirectory(configuration.DescriptorDirectory)
+	plugins, problems, err := scanDirectory(configuration.DescriptorDirectory)
 
 	assert.Nil(t, err, "unexpected error reading directory", err)
+	assert.Empty(t, problems)
 	assert.Len(t, plugins, 4, "wrong number of plugins")
 }
 
 func TestIfPluginMetadataIsRead(t *testing.T) {
 	configuration := Configuration{DescriptorDirectory: "resources/test/plugins"}
 
-	plugins, _ := scanDirectory(configuration.DescriptorDirectory)
+	plugins, _, _ := scanDirectory(configuration.DescriptorDirectory)
 	plugin := findPluginByName(plugins, "scm-auth-ldap-plugin")
 
 	assert.Equal(t, "scm-auth-ldap-plugin", plugin.Name)
@@ -35,10 +39,38 @@ func TestIfPluginMetadataIsRead(t *testing.T) {
 	assert.Equal(t, "Cloudogu GmbH", plugin.Author)
 }
 
+func TestIfTranslationsAreRead(t *testing.T) {
+	plugins, problems, _ := scanDirectory("resources/test/plugins")
+
+	assert.Empty(t, problems)
+	ldap := findPluginByName(plugins, "scm-auth-ldap-plugin")
+	assert.Equal(t, "Authentication", ldap.CategoryLabel)
+	assert.Equal(t, PluginTranslation{Description: "LDAP Authentifizierung", CategoryLabel: "Authentifizierung"}, ldap.Translations["de"])
+	cas := findPluginByName(plugins, "scm-cas-plugin")
+	assert.Equal(t, "CAS Authentifizierung für SCM-Manager 2.x", cas.Translations["de"].Description)
+	assert.Equal(t, "Authentifizierung", cas.Translations["de"].CategoryLabel)
+}
+
+func TestDuplicateTranslationIsReported(t *testing.T) {
+	directory := t.TempDir()
+	pluginDirectory := filepath.Join(directory, "scm-mail-plugin")
+	assert.NoError(t, os.Mkdir(pluginDirectory, 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(pluginDirectory, "plugin.yml"), []byte("name: scm-mail-plugin\ntranslations:\n  de:\n    displayName: Mail\n"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(pluginDirectory, "plugin_de.yml"), []byte("displayName: E-Mail\n"), 0644))
+
+	plugins, problems, _ := scanDirectory(directory)
+
+	assert.Equal(t, "Mail", plugins[0].Translations["de"].DisplayName)
+	assert.Equal(t, Problems{{
+		File:    filepath.Join(pluginDirectory, "plugin_de.yml"),
+		Message: "translation for de is already defined in plugin.yml",
+	}}, problems)
+}
+
 func TestIfReleasesAreRead(t *testing.T) {
 	configuration := Configuration{DescriptorDirectory: "resources/test/plugins"}
 
-	plugins, _ := scanDirectory(configuration.DescriptorDirectory)
+	plugins, _, _ := scanDirectory(configuration.DescriptorDirectory)
 	plugin := findPluginByName(plugins, "scm-cas-plugin")
 
 	assert.Equal(t, 2, len(plugin.Releases), "wrong number of releases")
@@ -54,7 +86,7 @@ func TestIfReleasesAreRead(t *testing.T) {
 func TestReleasesAreSorted(t *testing.T) {
 	configuration := Configuration{DescriptorDirectory: "resources/test/plugins"}
 
-	plugins, _ := scanDirectory(configuration.DescriptorDirectory)
+	plugins, _, _ := scanDirectory(configuration.DescriptorDirectory)
 	plugin := findPluginByName(plugins, "scm-script-plugin")
 
 	assert.Equal(t, 3, len(plugin.Releases), "wrong number of releases")
@@ -66,13 +98,74 @@ func TestReleasesAreSorted(t *testing.T) {
 func TestIfDependenciesAreRead(t *testing.T) {
 	configuration := Configuration{DescriptorDirectory: "resources/test/plugins"}
 
-	plugins, _ := scanDirectory(configuration.DescriptorDirectory)
+	plugins, _, _ := scanDirectory(configuration.DescriptorDirectory)
 	plugin := findPluginByName(plugins, "scm-cas-plugin")
 
 	release := plugin.Releases[0]
-	assert.Equal(t, []string{"scm-mail-plugin"}, release.Dependencies, "wrong dependencies for plugin")
-	assert.Equal(t, []string{"scm-review-plugin"}, release.OptionalDependencies, "wrong optional dependencies for plugin")
+	assert.Equal(t, []string{"scm-mail-plugin"}, release.Dependencies.Names(), "wrong dependencies for plugin")
+	assert.Equal(t, []string{"scm-review-plugin"}, release.OptionalDependencies.Names(), "wrong optional dependencies for plugin")
+
+}
+
+func TestBrokenReleasesAreSkippedAndReported(t *testing.T) {
+	plugins, problems, err := scanDirectory("resources/test/validate/broken/plugins")
+
+	assert.NoError(t, err)
+	assert.Len(t, plugins, 1)
+	plugin := findPluginByName(plugins, "scm-broken-plugin")
+	assert.Len(t, plugin.Releases, 1)
+	assert.Equal(t, "1.0.0", plugin.Releases[0].Version)
+
+	releases := "resources/test/validate/broken/plugins/scm-broken-plugin/releases/"
+	assert.Contains(t, problems, Problem{File: releases + "next.yml", Line: 2, Field: "tag", Message: "next is not a valid version"})
+	assert.Contains(t, problems, Problem{File: releases + "syntax.yml", Line: 2, Message: "did not find expected ',' or ']'"})
+	assert.Contains(t, problems, Problem{
+		File:    releases + "1-0-0.yml",
+		Line:    2,
+		Field:   "tag",
+		Message: "duplicate tag 1.0.0, already defined in " + releases + "1-0-0-again.yml",
+	})
+}
+
+func TestPluginWithoutNameIsSkippedAndReported(t *testing.T) {
+	plugins, problems, err := scanDirectory("resources/test/validate/broken/plugins")
+
+	assert.NoError(t, err)
+	assert.Nil(t, findPluginByName(plugins, ""))
+	assert.Contains(t, problems, Problem{
+		File:    "resources/test/validate/broken/plugins/scm-unparseable-plugin/plugin.yml",
+		Field:   "name",
+		Message: "name is missing",
+	})
+}
+
+func TestReadReleaseFailsForBrokenYaml(t *testing.T) {
+	_, _, err := readRelease("resources/test/validate/broken/plugins/scm-broken-plugin/releases/syntax.yml")
+
+	assert.Error(t, err)
+}
+
+func TestIfDependencyVersionsAreRead(t *testing.T) {
+	plugins, _, _ := scanDirectory("resources/test/dependencies/plugins")
+	plugin := findPluginByName(plugins, "scm-review-plugin")
+
+	release := plugin.Releases[1]
+	assert.Equal(t, "2.0.0", release.Version)
+	assert.Equal(t, []string{"scm-mail-plugin"}, release.Dependencies.Names())
+	assert.Equal(t, map[string]string{"scm-mail-plugin": ">=2.3.0"}, release.Dependencies.Versions())
+	assert.Equal(t, []string{"scm-landingpage-plugin"}, release.OptionalDependencies.Names())
+	assert.Empty(t, release.OptionalDependencies.Versions())
+}
+
+func TestUnsatisfiableDependencyVersionsAreReported(t *testing.T) {
+	_, problems, _ := scanDirectory("resources/test/dependencies/plugins")
 
+	assert.Equal(t, Problems{{
+		File:    "resources/test/dependencies/plugins/scm-review-plugin/releases/3-0-0.yml",
+		Line:    7,
+		Field:   "dependencies",
+		Message: "no release of scm-mail-plugin satisfies >=3.0.0",
+	}}, problems)
 }
 
 func findPluginByName(plugins []Plugin, name string) *Plugin {
@@ -83,3 +176,62 @@ func findPluginByName(plugins []Plugin, name string) *Plugin {
 	}
 	return nil
 }
+
+func TestUnknownChannelIsReported(t *testing.T) {
+	var problems Problems
+	release := Release{Version: "1.0.0", Url: "https://download.scm-manager.org/a.smp", Checksum: "abc", Channel: "alpha"}
+
+	checkRelease(&problems, "1-0-0.yml", []byte("channel: alpha\n"), "scm-a-plugin", release)
+
+	assert.Len(t, problems, 1)
+	assert.Equal(t, "1-0-0.yml:1: channel: alpha is not a valid channel", problems[0].String())
+}
+
+func TestInvalidRolloutIsReported(t *testing.T) {
+	var problems Problems
+	release := Release{
+		Version:  "1.0.0",
+		Url:      "https://download.scm-manager.org/a.smp",
+		Checksum: "abc",
+		Rollout:  &Rollout{Percentage: 120, Start: "tomorrow"},
+	}
+
+	checkRelease(&problems, "1-0-0.yml", []byte("rollout:\n  percentage: 120\n  start: tomorrow\n"), "scm-a-plugin", release)
+
+	assert.Len(t, problems, 2)
+	assert.Equal(t, "1-0-0.yml:2: rollout.percentage: 120 is not between 0 and 100", problems[0].String())
+	assert.Equal(t, "1-0-0.yml:3: rollout.start: tomorrow is not a valid RFC 3339 timestamp", problems[1].String())
+}
+
+func TestInvalidAdvisoryIsReported(t *testing.T) {
+	var problems Problems
+	release := Release{
+		Version:    "1.0.0",
+		Url:        "https://download.scm-manager.org/a.smp",
+		Checksum:   "abc",
+		Advisories: []Advisory{{Severity: "urgent", FixedIn: "soon"}},
+	}
+
+	checkAdvisories(&problems, "1-0-0.yml", []byte("advisories:\n  - severity: urgent\n    fixedIn: soon\n"), release)
+
+	assert.Len(t, problems, 2)
+	assert.Equal(t, "1-0-0.yml:2: advisories.severity: urgent is not a valid severity", problems[0].String())
+	assert.Equal(t, "1-0-0.yml:3: advisories.fixedIn: soon is not a valid version", problems[1].String())
+}
+
+func TestReleaseWithInvalidAdvisoryIsNotSkipped(t *testing.T) {
+	directory := t.TempDir()
+	releaseDirectory := filepath.Join(directory, "scm-mail-plugin", "releases")
+	assert.NoError(t, os.MkdirAll(releaseDirectory, 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(directory, "scm-mail-plugin", "plugin.yml"), []byte("name: scm-mail-plugin\n"), 0644))
+	release := "tag: 1.0.0\nurl: https://download.scm-manager.org/a.smp\nchecksum: abc\nyanked: true\nadvisories:\n  - severity: urgent\n"
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(releaseDirectory, "1-0-0.yml"), []byte(release), 0644))
+
+	plugins, problems, err := scanDirectory(directory)
+
+	assert.NoError(t, err)
+	assert.Len(t, problems, 1)
+	assert.Equal(t, "advisories.severity", problems[0].Field)
+	assert.Len(t, plugins[0].Releases, 1)
+	assert.True(t, plugins[0].Releases[0].Yanked)
+}
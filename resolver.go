@@ -0,0 +1,254 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strings"
+)
+
+const (
+	MissingReasonNotFound            = "not found"
+	MissingReasonNoCompatibleRelease = "no compatible release"
+	MissingReasonVersionConflict     = "version conflict"
+	resolveStateVisiting             = 1
+	resolveStateVisited              = 2
+	// resolveMaxSteps limits the search for releases without version conflicts
+	resolveMaxSteps = 10000
+)
+
+type MissingDependency struct {
+	Name       string `json:"name"`
+	Versions   string `json:"versions,omitempty"`
+	RequiredBy string `json:"requiredBy,omitempty"`
+	Reason     string `json:"reason"`
+}
+
+type ResolveResult struct {
+	Plugins []PluginResult      `json:"plugins"`
+	Missing []MissingDependency `json:"missing"`
+	Cycles  [][]string          `json:"cycles"`
+}
+
+func (r ResolveResult) IsResolved() bool {
+	return len(r.Missing) == 0 && len(r.Cycles) == 0
+}
+
+func NewResolveHandler(catalogHolder *CatalogHolder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		requestConditions, err := extractRequestConditions(r)
+		if err != nil {
+			log.Println("invalid request conditions:", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		requested := extractRequestedPlugins(r)
+		if len(requested) == 0 {
+			writeJsonError(w, http.StatusBadRequest, "query parameter plugins is required")
+			return
+		}
+
+		authenticated := r.Context().Value("subject") != nil
+		resolver := dependencyResolver{
+			catalog:       catalogHolder.Get(),
+			conditions:    requestConditions,
+			generator:     NewUrlGenerator(*r),
+			authenticated: authenticated,
+		}
+		result := resolver.resolve(requested)
+
+		status := http.StatusOK
+		if !result.IsResolved() {
+			status = http.StatusConflict
+		}
+		writeJson(w, status, result)
+	}
+}
+
+func extractRequestedPlugins(r *http.Request) []string {
+	var plugins []string
+	for _, value := range r.Form["plugins"] {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name != "" {
+				plugins = append(plugins, name)
+			}
+		}
+	}
+	return plugins
+}
+
+type dependencyResolver struct {
+	catalog       *Catalog
+	conditions    RequestConditions
+	generator     UrlGenerator
+	authenticated bool
+
+	releases map[string]Release
+	greedy   bool
+	steps    int
+	states   map[string]int
+	stack    []string
+	result   ResolveResult
+}
+
+// requirement is a dependency, which has to be satisfied by the install plan
+type requirement struct {
+	dependency Dependency
+	requiredBy string
+}
+
+// resolve computes an install plan for the requested plugins, in which every plugin is preceded by its dependencies.
+func (d *dependencyResolver) resolve(requested []string) ResolveResult {
+	d.releases = make(map[string]Release)
+	d.states = make(map[string]int)
+	d.result = ResolveResult{
+		Plugins: []PluginResult{},
+		Missing: []MissingDependency{},
+		Cycles:  [][]string{},
+	}
+
+	var requirements []requirement
+	for _, name := range requested {
+		requirements = append(requirements, requirement{dependency: Dependency{Name: name}})
+	}
+
+	// first collect all plugins which have to be installed, afterwards order them
+	if !d.collect(requirements) {
+		// no combination of releases satisfies all version ranges,
+		// so the conflicts of the newest releases are reported
+		d.releases = make(map[string]Release)
+		d.result.Missing = d.result.Missing[:0]
+		d.greedy = true
+		d.collect(requirements)
+	}
+	for _, name := range requested {
+		d.order(name)
+	}
+	return d.result
+}
+
+// collect selects a release for every requirement. The newest satisfying release is tried first, older releases are
+// only selected if the newer ones lead to a version conflict. In greedy mode only the newest release is tried and
+// conflicts are reported as missing dependencies.
+func (d *dependencyResolver) collect(requirements []requirement) bool {
+	if len(requirements) == 0 {
+		return true
+	}
+	d.steps++
+	if d.steps > resolveMaxSteps && !d.greedy {
+		return false
+	}
+
+	dependency := requirements[0].dependency
+	requiredBy := requirements[0].requiredBy
+	remaining := requirements[1:]
+
+	if release, ok := d.releases[dependency.Name]; ok {
+		if !dependency.IsSatisfiedBy(release.Version) {
+			if !d.greedy {
+				return false
+			}
+			d.addMissing(dependency, requiredBy, MissingReasonVersionConflict)
+		}
+		return d.collect(remaining)
+	}
+
+	plugin, ok := d.catalog.FindPlugin(dependency.Name)
+	if !ok {
+		d.addMissing(dependency, requiredBy, MissingReasonNotFound)
+		return d.collect(remaining)
+	}
+	releases := findSatisfyingReleases(findCompatibleReleases(plugin, d.conditions), dependency)
+	if len(releases) == 0 {
+		d.addMissing(dependency, requiredBy, MissingReasonNoCompatibleRelease)
+		return d.collect(remaining)
+	}
+
+	missingCount := len(d.result.Missing)
+	for _, release := range releases {
+		d.releases[dependency.Name] = release
+
+		var next []requirement
+		for _, transitiveDependency := range release.Dependencies {
+			next = append(next, requirement{dependency: transitiveDependency, requiredBy: dependency.Name})
+		}
+		if d.collect(append(next, remaining...)) {
+			return true
+		}
+
+		delete(d.releases, dependency.Name)
+		d.result.Missing = d.result.Missing[:missingCount]
+	}
+	return false
+}
+
+func findSatisfyingReleases(releases []Release, dependency Dependency) []Release {
+	var satisfying []Release
+	for _, release := range releases {
+		if dependency.IsSatisfiedBy(release.Version) {
+			satisfying = append(satisfying, release)
+		}
+	}
+	return satisfying
+}
+
+func (d *dependencyResolver) addMissing(dependency Dependency, requiredBy string, reason string) {
+	for _, missing := range d.result.Missing {
+		if missing.Name == dependency.Name {
+			return
+		}
+	}
+	d.result.Missing = append(d.result.Missing, MissingDependency{
+		Name:       dependency.Name,
+		Versions:   dependency.Versions.Value,
+		RequiredBy: requiredBy,
+		Reason:     reason,
+	})
+}
+
+func (d *dependencyResolver) order(name string) {
+	release, ok := d.releases[name]
+	if !ok {
+		return
+	}
+
+	switch d.states[name] {
+	case resolveStateVisited:
+		return
+	case resolveStateVisiting:
+		d.addCycle(name)
+		return
+	}
+
+	d.states[name] = resolveStateVisiting
+	d.stack = append(d.stack, name)
+
+	// optional dependencies are only installed if they are part of the plan anyway,
+	// but in this case they have to be installed first; a cycle over an optional dependency
+	// is no error, because it can be installed afterwards
+	for _, dependency := range release.Dependencies {
+		d.order(dependency.Name)
+	}
+	for _, dependency := range release.OptionalDependencies {
+		if d.states[dependency.Name] != resolveStateVisiting {
+			d.order(dependency.Name)
+		}
+	}
+
+	d.stack = d.stack[:len(d.stack)-1]
+	d.states[name] = resolveStateVisited
+
+	plugin, _ := d.catalog.FindPlugin(name)
+	d.result.Plugins = append(d.result.Plugins, createPluginResult(plugin, release, d.generator, d.authenticated))
+}
+
+func (d *dependencyResolver) addCycle(name string) {
+	for i := len(d.stack) - 1; i >= 0; i-- {
+		if d.stack[i] == name {
+			cycle := append([]string{}, d.stack[i:]...)
+			d.result.Cycles = append(d.result.Cycles, append(cycle, name))
+			return
+		}
+	}
+}
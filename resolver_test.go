@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+var resolverTestData = []Plugin{
+	resolverTestPlugin("scm-review-plugin", []string{"scm-mail-plugin"}, []string{"scm-landingpage-plugin"}),
+	resolverTestPlugin("scm-mail-plugin", []string{"scm-notify-plugin"}, nil),
+	resolverTestPlugin("scm-notify-plugin", nil, nil),
+	resolverTestPlugin("scm-landingpage-plugin", nil, nil),
+	resolverTestPlugin("scm-cycle-a-plugin", []string{"scm-cycle-b-plugin"}, nil),
+	resolverTestPlugin("scm-cycle-b-plugin", []string{"scm-cycle-a-plugin"}, nil),
+	resolverTestPlugin("scm-broken-plugin", []string{"scm-unknown-plugin", "scm-future-plugin"}, nil),
+	{
+		Name: "scm-future-plugin",
+		Releases: []Release{
+			{Version: "1.0.0", Conditions: Conditions{MinVersion: "3.0.0"}},
+		},
+	},
+}
+
+func resolverTestPlugin(name string, dependencyNames []string, optionalDependencyNames []string) Plugin {
+	var dependencies, optionalDependencies Dependencies
+	for _, dependency := range dependencyNames {
+		dependencies = append(dependencies, Dependency{Name: dependency})
+	}
+	for _, dependency := range optionalDependencyNames {
+		optionalDependencies = append(optionalDependencies, Dependency{Name: dependency})
+	}
+	return Plugin{
+		Name: name,
+		Releases: []Release{
+			{Version: "2.0.0", Conditions: Conditions{MinVersion: "2.1.0"}, Dependencies: dependencies, OptionalDependencies: optionalDependencies},
+			{Version: "1.0.0", Conditions: Conditions{MinVersion: "2.0.0"}, Dependencies: dependencies, OptionalDependencies: optionalDependencies},
+		},
+	}
+}
+
+func resolve(t *testing.T, url string) (int, ResolveResult) {
+	rr := initRouter(t, url, "", NewResolveHandler(staticCatalog(NewCatalog(resolverTestData, nil))))
+
+	var result ResolveResult
+	err := json.Unmarshal(rr.Body.Bytes(), &result)
+	assert.NoError(t, err)
+	return rr.Code, result
+}
+
+func pluginNamesOf(results []PluginResult) []string {
+	var names []string
+	for _, result := range results {
+		names = append(names, result.Name)
+	}
+	return names
+}
+
+func TestResolveHandlerOrdersDependenciesFirst(t *testing.T) {
+	code, result := resolve(t, "/api/v1/resolve/2.1.0?plugins=scm-review-plugin")
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"scm-notify-plugin", "scm-mail-plugin", "scm-review-plugin"}, pluginNamesOf(result.Plugins))
+	assert.Empty(t, result.Missing)
+	assert.Empty(t, result.Cycles)
+}
+
+func TestResolveHandlerPicksNewestCompatibleRelease(t *testing.T) {
+	_, result := resolve(t, "/api/v1/resolve/2.0.5?plugins=scm-mail-plugin")
+	assert.Equal(t, "1.0.0", result.Plugins[0].Version)
+
+	_, result = resolve(t, "/api/v1/resolve/2.1.0?plugins=scm-mail-plugin")
+	assert.Equal(t, "2.0.0", result.Plugins[0].Version)
+}
+
+func TestResolveHandlerOrdersRequestedOptionalDependenciesFirst(t *testing.T) {
+	_, result := resolve(t, "/api/v1/resolve/2.1.0?plugins=scm-review-plugin,scm-landingpage-plugin")
+
+	assert.Equal(t, []string{"scm-notify-plugin", "scm-mail-plugin", "scm-landingpage-plugin", "scm-review-plugin"}, pluginNamesOf(result.Plugins))
+}
+
+func TestResolveHandlerAcceptsRepeatedParameter(t *testing.T) {
+	_, result := resolve(t, "/api/v1/resolve/2.1.0?plugins=scm-notify-plugin&plugins=scm-landingpage-plugin")
+
+	assert.Equal(t, []string{"scm-notify-plugin", "scm-landingpage-plugin"}, pluginNamesOf(result.Plugins))
+}
+
+func TestResolveHandlerDetectsCycles(t *testing.T) {
+	code, result := resolve(t, "/api/v1/resolve/2.1.0?plugins=scm-cycle-a-plugin")
+
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Equal(t, [][]string{{"scm-cycle-a-plugin", "scm-cycle-b-plugin", "scm-cycle-a-plugin"}}, result.Cycles)
+}
+
+func TestResolveHandlerReportsMissingDependencies(t *testing.T) {
+	code, result := resolve(t, "/api/v1/resolve/2.1.0?plugins=scm-broken-plugin")
+
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Equal(t, []MissingDependency{
+		{Name: "scm-unknown-plugin", RequiredBy: "scm-broken-plugin", Reason: MissingReasonNotFound},
+		{Name: "scm-future-plugin", RequiredBy: "scm-broken-plugin", Reason: MissingReasonNoCompatibleRelease},
+	}, result.Missing)
+}
+
+func TestResolveHandlerPicksOlderReleaseToAvoidVersionConflicts(t *testing.T) {
+	plugins, _, err := scanDirectory("resources/test/dependencies/plugins")
+	assert.NoError(t, err)
+	handler := NewResolveHandler(staticCatalog(NewCatalog(plugins, nil)))
+
+	rr := initRouter(t, "/api/v1/resolve/2.0.0?plugins=scm-mail-plugin,scm-review-plugin", "", handler)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var result ResolveResult
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &result))
+	assert.Equal(t, []string{"scm-mail-plugin", "scm-review-plugin"}, pluginNamesOf(result.Plugins))
+	assert.Equal(t, "2.3.1", result.Plugins[0].Version)
+	assert.Equal(t, "2.0.0", result.Plugins[1].Version)
+	assert.Empty(t, result.Missing)
+}
+
+func TestResolveHandlerPicksOlderDependencyToAvoidVersionConflicts(t *testing.T) {
+	mail := resolverTestPlugin("scm-mail-plugin", nil, nil)
+	review := resolverTestPlugin("scm-review-plugin", nil, nil)
+	review.Releases[0].Dependencies = Dependencies{{Name: "scm-mail-plugin", Versions: MustParseVersionRange("<2.0.0")}}
+	review.Releases[1].Dependencies = review.Releases[0].Dependencies
+	handler := NewResolveHandler(staticCatalog(NewCatalog([]Plugin{mail, review}, nil)))
+
+	rr := initRouter(t, "/api/v1/resolve/2.1.0?plugins=scm-mail-plugin,scm-review-plugin", "", handler)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var result ResolveResult
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &result))
+	assert.Equal(t, []string{"scm-mail-plugin", "scm-review-plugin"}, pluginNamesOf(result.Plugins))
+	assert.Equal(t, "1.0.0", result.Plugins[0].Version)
+	assert.Empty(t, result.Missing)
+}
+
+func TestResolveHandlerReportsVersionConflicts(t *testing.T) {
+	mail := resolverTestPlugin("scm-mail-plugin", nil, nil)
+	review := resolverTestPlugin("scm-review-plugin", nil, nil)
+	review.Releases[0].Dependencies = Dependencies{{Name: "scm-mail-plugin", Versions: MustParseVersionRange("<2.0.0")}}
+	review.Releases[1].Dependencies = review.Releases[0].Dependencies
+	landingpage := resolverTestPlugin("scm-landingpage-plugin", nil, nil)
+	landingpage.Releases[0].Dependencies = Dependencies{{Name: "scm-mail-plugin", Versions: MustParseVersionRange(">=2.0.0")}}
+	landingpage.Releases[1].Dependencies = landingpage.Releases[0].Dependencies
+	handler := NewResolveHandler(staticCatalog(NewCatalog([]Plugin{mail, review, landingpage}, nil)))
+
+	rr := initRouter(t, "/api/v1/resolve/2.1.0?plugins=scm-review-plugin,scm-landingpage-plugin", "", handler)
+
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	var result ResolveResult
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &result))
+	assert.Equal(t, []MissingDependency{
+		{Name: "scm-mail-plugin", Versions: ">=2.0.0", RequiredBy: "scm-landingpage-plugin", Reason: MissingReasonVersionConflict},
+	}, result.Missing)
+}
+
+func TestResolveHandlerIgnoresCyclesOfOptionalDependencies(t *testing.T) {
+	mail := resolverTestPlugin("scm-mail-plugin", nil, []string{"scm-review-plugin"})
+	review := resolverTestPlugin("scm-review-plugin", []string{"scm-mail-plugin"}, nil)
+	handler := NewResolveHandler(staticCatalog(NewCatalog([]Plugin{mail, review}, nil)))
+
+	rr := initRouter(t, "/api/v1/resolve/2.1.0?plugins=scm-review-plugin", "", handler)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var result ResolveResult
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &result))
+	assert.Equal(t, []string{"scm-mail-plugin", "scm-review-plugin"}, pluginNamesOf(result.Plugins))
+	assert.Empty(t, result.Cycles)
+}
+
+func TestResolveHandlerPicksNewestReleaseSatisfyingDependencyVersions(t *testing.T) {
+	mail := resolverTestPlugin("scm-mail-plugin", nil, nil)
+	review := resolverTestPlugin("scm-review-plugin", nil, nil)
+	review.Releases[0].Dependencies = Dependencies{{Name: "scm-mail-plugin", Versions: MustParseVersionRange("<2.0.0")}}
+	handler := NewResolveHandler(staticCatalog(NewCatalog([]Plugin{mail, review}, nil)))
+
+	rr := initRouter(t, "/api/v1/resolve/2.1.0?plugins=scm-review-plugin", "", handler)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var result ResolveResult
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &result))
+	assert.Equal(t, []string{"scm-mail-plugin", "scm-review-plugin"}, pluginNamesOf(result.Plugins))
+	assert.Equal(t, "1.0.0", result.Plugins[0].Version)
+	assert.Equal(t, "2.0.0", result.Plugins[1].Version)
+}
+
+func TestResolveHandlerRequiresPlugins(t *testing.T) {
+	rr := initRouter(t, "/api/v1/resolve/2.1.0", "", NewResolveHandler(testCatalog()))
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"hash/fnv"
+	"time"
+)
+
+// Rollout limits the offering of a release to a percentage of the instances, starting at a given time.
+type Rollout struct {
+	Percentage int    `yaml:"percentage" json:"percentage"`
+	Start      string `yaml:"start,omitempty" json:"start,omitempty"`
+}
+
+// UnmarshalYAML offers the release to all instances, if the rollout only defines a start.
+func (r *Rollout) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain Rollout
+	rollout := plain{Percentage: 100}
+	if err := unmarshal(&rollout); err != nil {
+		return err
+	}
+	*r = Rollout(rollout)
+	return nil
+}
+
+// now is replaced in tests
+var now = time.Now
+
+// isRolledOutTo decides deterministically, whether the release is offered to the given instance.
+// Instances without an id only receive releases which are rolled out completely.
+func isRolledOutTo(pluginName string, release Release, instance string) bool {
+	rollout := release.Rollout
+	if rollout == nil {
+		return true
+	}
+	if rollout.Start != "" {
+		start, err := time.Parse(time.RFC3339, rollout.Start)
+		if err != nil || now().Before(start) {
+			return false
+		}
+	}
+	if rollout.Percentage >= 100 {
+		return true
+	}
+	if instance == "" || rollout.Percentage <= 0 {
+		return false
+	}
+	return rolloutBucket(pluginName, release.Version, instance) < rollout.Percentage
+}
+
+// rolloutBucket assigns the instance to one of 100 buckets. The release is part of the key, so that not always
+// the same instances receive new releases first.
+func rolloutBucket(pluginName string, version string, instance string) int {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(pluginName + "@" + version + "@" + instance))
+	return int(hash.Sum32() % 100)
+}
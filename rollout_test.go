@@ -0,0 +1,99 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestReleaseWithoutRolloutIsOfferedToEveryone(t *testing.T) {
+	assert.True(t, isRolledOutTo("scm-a-plugin", Release{Version: "1.0.0"}, ""))
+}
+
+func TestRolloutIsDeterministicPerInstance(t *testing.T) {
+	release := Release{Version: "1.0.0", Rollout: &Rollout{Percentage: 50}}
+
+	for i := 0; i < 10; i++ {
+		instance := "instance-" + strconv.Itoa(i)
+		assert.Equal(t, isRolledOutTo("scm-a-plugin", release, instance), isRolledOutTo("scm-a-plugin", release, instance))
+	}
+}
+
+func TestRolloutReachesRoughlyThePercentageOfInstances(t *testing.T) {
+	release := Release{Version: "1.0.0", Rollout: &Rollout{Percentage: 20}}
+
+	count := 0
+	for i := 0; i < 1000; i++ {
+		if isRolledOutTo("scm-a-plugin", release, "instance-"+strconv.Itoa(i)) {
+			count++
+		}
+	}
+	assert.InDelta(t, 200, count, 50)
+}
+
+func TestRolloutIsNotOfferedToAnonymousInstances(t *testing.T) {
+	assert.False(t, isRolledOutTo("scm-a-plugin", Release{Version: "1.0.0", Rollout: &Rollout{Percentage: 99}}, ""))
+	assert.True(t, isRolledOutTo("scm-a-plugin", Release{Version: "1.0.0", Rollout: &Rollout{Percentage: 100}}, ""))
+}
+
+func TestRolloutIsNotOfferedBeforeStart(t *testing.T) {
+	now = func() time.Time { return time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC) }
+	defer func() { now = time.Now }()
+
+	assert.False(t, isRolledOutTo("scm-a-plugin", Release{Version: "1.0.0", Rollout: &Rollout{Percentage: 100, Start: "2021-03-02T00:00:00Z"}}, ""))
+	assert.True(t, isRolledOutTo("scm-a-plugin", Release{Version: "1.0.0", Rollout: &Rollout{Percentage: 100, Start: "2021-03-01T00:00:00Z"}}, ""))
+}
+
+func TestRolloutWithOnlyStartIsOfferedToEveryoneAfterStart(t *testing.T) {
+	now = func() time.Time { return time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC) }
+	defer func() { now = time.Now }()
+
+	var release Release
+	err := yaml.Unmarshal([]byte("tag: 1.0.0\nrollout:\n  start: 2020-01-01T00:00:00Z\n"), &release)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 100, release.Rollout.Percentage)
+	assert.True(t, isRolledOutTo("scm-a-plugin", release, "instance-1"))
+	assert.True(t, isRolledOutTo("scm-a-plugin", release, ""))
+}
+
+func TestRolloutKeepsExplicitPercentage(t *testing.T) {
+	var release Release
+	err := yaml.Unmarshal([]byte("tag: 1.0.0\nrollout:\n  percentage: 0\n"), &release)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 0, release.Rollout.Percentage)
+
+	data, err := yaml.Marshal(release.Rollout)
+	assert.NoError(t, err)
+	assert.Equal(t, "percentage: 0\n", string(data))
+}
+
+func TestPluginHandlerOffersPreviousReleaseToInstancesOutsideOfRollout(t *testing.T) {
+	plugin := Plugin{
+		Name: "scm-rollout-plugin",
+		Releases: []Release{
+			{Version: "1.1.0", Rollout: &Rollout{Percentage: 50}},
+			{Version: "1.0.0"},
+		},
+	}
+	catalog := staticCatalog(NewCatalog([]Plugin{plugin}, nil))
+
+	included, excluded := "", ""
+	for i := 0; i < 100 && (included == "" || excluded == ""); i++ {
+		instance := "instance-" + strconv.Itoa(i)
+		if rolloutBucket(plugin.Name, "1.1.0", instance) < 50 {
+			included = instance
+		} else {
+			excluded = instance
+		}
+	}
+
+	rr := initRouter(t, "/api/v1/plugins/2.0.0?instance="+included, "", NewPluginHandler(catalog))
+	assert.Contains(t, rr.Body.String(), `"version":"1.1.0"`)
+
+	rr = initRouter(t, "/api/v1/plugins/2.0.0?instance="+excluded, "", NewPluginHandler(catalog))
+	assert.Contains(t, rr.Body.String(), `"version":"1.0.0"`)
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/pkg/errors"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// emptyPayloadHash is the sha256 checksum of an empty request body
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// s3ArtifactStore reads artifacts with urls like s3://bucket/path/to/plugin.smp from a S3 compatible object storage.
+// The objects are requested in path style and the requests are signed with AWS signature version 4.
+type s3ArtifactStore struct {
+	endpoint  *url.URL
+	region    string
+	accessKey string
+	secretKey string
+	client    *http.Client
+}
+
+func newS3ArtifactStore(configuration S3Configuration) (*s3ArtifactStore, error) {
+	endpoint, err := url.Parse(configuration.Endpoint)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not parse s3 endpoint %s", configuration.Endpoint)
+	}
+	if endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, errors.Errorf("s3 endpoint %s has to be an absolute url", configuration.Endpoint)
+	}
+	return &s3ArtifactStore{
+		endpoint:  endpoint,
+		region:    configuration.Region,
+		accessKey: configuration.AccessKey,
+		secretKey: configuration.SecretKey,
+		client:    newArtifactHttpClient(artifactReadTimeout),
+	}, nil
+}
+
+func (s *s3ArtifactStore) Open(location string) (*Artifact, error) {
+	objectUrl, err := url.Parse(location)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not parse s3 url %s", location)
+	}
+	bucket := objectUrl.Host
+	key := strings.TrimPrefix(objectUrl.Path, "/")
+	if bucket == "" || key == "" {
+		return nil, errors.Errorf("s3 url %s has to contain a bucket and a key", location)
+	}
+
+	requestUrl := *s.endpoint
+	requestUrl.Path = strings.TrimSuffix(s.endpoint.Path, "/") + "/" + bucket + "/" + key
+	requestUrl.RawPath = strings.TrimSuffix(s.endpoint.EscapedPath(), "/") + "/" + awsEscape(bucket) + "/" + awsEscapePath(key)
+
+	req, err := http.NewRequest(http.MethodGet, requestUrl.String(), nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not create request for %s", location)
+	}
+	req.Header.Set("x-amz-content-sha256", emptyPayloadHash)
+	signV4(req, s.accessKey, s.secretKey, s.region, now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not request %s", location)
+	}
+	return artifactFromResponse(resp, location)
+}
+
+// signV4 adds the authorization header for AWS signature version 4 to the request. The host and all headers which
+// are already set on the request are signed. The payload has to be set as x-amz-content-sha256 header.
+func signV4(req *http.Request, accessKey string, secretKey string, region string, timestamp time.Time) {
+	amzDate := timestamp.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("x-amz-date", amzDate)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		req.Header.Get("x-amz-content-sha256"),
+	}, "\n")
+
+	scope := date + "/" + region + "/s3/aws4_request"
+	canonicalRequestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(canonicalRequestHash[:]),
+	}, "\n")
+
+	key := hmacSha256([]byte("AWS4"+secretKey), date)
+	key = hmacSha256(key, region)
+	key = hmacSha256(key, "s3")
+	key = hmacSha256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSha256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature,
+	))
+}
+
+func hmacSha256(key []byte, value string) []byte {
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(value))
+	return mac.Sum(nil)
+}
+
+// awsEscapePath escapes every segment of the path as required for the canonical request.
+func awsEscapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = awsEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// awsEscape escapes all characters except the unreserved ones of RFC 3986.
+func awsEscape(value string) string {
+	var escaped strings.Builder
+	for _, b := range []byte(value) {
+		if (b >= 'A' && b <= 'Z') || (b >= 'a' && b <= 'z') || (b >= '0' && b <= '9') || b == '-' || b == '_' || b == '.' || b == '~' {
+			escaped.WriteByte(b)
+		} else {
+			escaped.WriteString(fmt.Sprintf("%%%02X", b))
+		}
+	}
+	return escaped.String()
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"github.com/pkg/errors"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// forbiddenSvgElements are removed together with their content, because they can execute code, embed foreign markup
+// or change attributes like href after sanitizing (animations)
+var forbiddenSvgElements = map[string]bool{
+	"script":           true,
+	"foreignobject":    true,
+	"iframe":           true,
+	"embed":            true,
+	"object":           true,
+	"animate":          true,
+	"animatemotion":    true,
+	"animatetransform": true,
+	"set":              true,
+}
+
+var (
+	cssUrlPattern    = regexp.MustCompile(`(?i)url\(\s*['"]?([^'")]*)['"]?\s*\)?`)
+	cssImportPattern = regexp.MustCompile(`(?i)@import[^;]*;?`)
+)
+
+// sanitizeSvg removes scripts, event handlers and references to external resources from the svg.
+// Comments, doctypes and processing instructions other than the xml declaration are dropped as well.
+func sanitizeSvg(data []byte) ([]byte, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var out bytes.Buffer
+	skipDepth := 0
+	root := true
+	var open []string
+	// the text of a style element is collected and sanitized as a whole, because comments and
+	// child elements are dropped and could otherwise be used to split forbidden css
+	var style *bytes.Buffer
+
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse svg")
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if skipDepth > 0 || style != nil || forbiddenSvgElements[strings.ToLower(t.Name.Local)] {
+				skipDepth++
+				continue
+			}
+			if root && t.Name.Local != "svg" {
+				return nil, errors.Errorf("root element is %s instead of svg", t.Name.Local)
+			}
+			root = false
+			open = append(open, qualifiedName(t.Name))
+			out.WriteString("<" + qualifiedName(t.Name))
+			for _, attr := range t.Attr {
+				if isUnsafeSvgAttribute(attr) {
+					continue
+				}
+				out.WriteString(" " + qualifiedName(attr.Name) + `="`)
+				if err := xml.EscapeText(&out, []byte(attr.Value)); err != nil {
+					return nil, err
+				}
+				out.WriteString(`"`)
+			}
+			out.WriteString(">")
+			if strings.ToLower(t.Name.Local) == "style" {
+				style = &bytes.Buffer{}
+			}
+		case xml.EndElement:
+			if skipDepth > 0 {
+				skipDepth--
+				continue
+			}
+			if style != nil {
+				if err := xml.EscapeText(&out, []byte(sanitizeSvgStyle(style.String()))); err != nil {
+					return nil, err
+				}
+				style = nil
+			}
+			// the raw tokens are not checked by the decoder, so we have to check the nesting on our own
+			if len(open) == 0 || open[len(open)-1] != qualifiedName(t.Name) {
+				return nil, errors.Errorf("unexpected end element %s", qualifiedName(t.Name))
+			}
+			open = open[:len(open)-1]
+			out.WriteString("</" + qualifiedName(t.Name) + ">")
+		case xml.CharData:
+			if skipDepth > 0 {
+				continue
+			}
+			if style != nil {
+				style.Write(t)
+				continue
+			}
+			if err := xml.EscapeText(&out, t); err != nil {
+				return nil, err
+			}
+		case xml.ProcInst:
+			if t.Target == "xml" {
+				out.WriteString("<?xml " + string(t.Inst) + "?>")
+			}
+		}
+	}
+
+	if root {
+		return nil, errors.New("svg does not contain any element")
+	}
+	if len(open) > 0 {
+		return nil, errors.Errorf("element %s is not closed", open[len(open)-1])
+	}
+	return out.Bytes(), nil
+}
+
+func qualifiedName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+func isUnsafeSvgAttribute(attr xml.Attr) bool {
+	name := strings.ToLower(attr.Name.Local)
+	if strings.HasPrefix(name, "on") {
+		return true
+	}
+	if name == "href" {
+		return !isSafeSvgReference(attr.Value)
+	}
+	if name == "style" && hasCssEscapes(attr.Value) {
+		return true
+	}
+	// style and presentation attributes like fill may reference resources with url()
+	for _, match := range cssUrlPattern.FindAllStringSubmatch(attr.Value, -1) {
+		if !isSafeSvgReference(match[1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// sanitizeSvgStyle removes imports and url references to external resources from the content of a style element.
+func sanitizeSvgStyle(css string) string {
+	if hasCssEscapes(css) {
+		return ""
+	}
+	css = cssImportPattern.ReplaceAllString(css, "")
+	return cssUrlPattern.ReplaceAllStringFunc(css, func(value string) string {
+		if isSafeSvgReference(cssUrlPattern.FindStringSubmatch(value)[1]) {
+			return value
+		}
+		return "none"
+	})
+}
+
+// hasCssEscapes returns true for css with escapes, because they could hide url references or imports.
+func hasCssEscapes(css string) bool {
+	return strings.Contains(css, `\`)
+}
+
+// isSafeSvgReference allows only references to elements of the same document and embedded raster images.
+func isSafeSvgReference(reference string) bool {
+	value := strings.ToLower(strings.TrimSpace(reference))
+	if strings.HasPrefix(value, "#") {
+		return true
+	}
+	return strings.HasPrefix(value, "data:image/") && !strings.HasPrefix(value, "data:image/svg")
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"testing"
+)
+
+func TestSanitizeSvgRemovesScriptsAndEventHandlers(t *testing.T) {
+	svg := `<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg" onload="alert(1)"><script>alert(2)</script>` +
+		`<foreignObject><div>html</div></foreignObject><rect width="10" onclick="alert(3)"/></svg>`
+
+	sanitized, err := sanitizeSvg([]byte(svg))
+
+	assert.NoError(t, err)
+	assert.Equal(t, `<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg"><rect width="10"></rect></svg>`, string(sanitized))
+}
+
+func TestSanitizeSvgKeepsLocalReferences(t *testing.T) {
+	svg := `<svg xmlns:xlink="http://www.w3.org/1999/xlink"><use xlink:href="#a"/><a href="javascript:alert(1)">x</a>` +
+		`<image href="https://evil.example.com/track.png"/><image href="data:image/png;base64,AAAA"/></svg>`
+
+	sanitized, err := sanitizeSvg([]byte(svg))
+
+	assert.NoError(t, err)
+	assert.Equal(t, `<svg xmlns:xlink="http://www.w3.org/1999/xlink"><use xlink:href="#a"></use><a>x</a>`+
+		`<image></image><image href="data:image/png;base64,AAAA"></image></svg>`, string(sanitized))
+}
+
+func TestSanitizeSvgRemovesAnimations(t *testing.T) {
+	svg := `<svg><a><animate attributeName="href" to="javascript:alert(1)"/><set attributeName="href" to="javascript:alert(2)"/>x</a>` +
+		`<rect/></svg>`
+
+	sanitized, err := sanitizeSvg([]byte(svg))
+
+	assert.NoError(t, err)
+	assert.Equal(t, `<svg><a>x</a><rect></rect></svg>`, string(sanitized))
+}
+
+func TestSanitizeSvgRemovesImportsAndExternalUrlsFromStyles(t *testing.T) {
+	svg := `<svg><style>@import url(https://evil.example.com/track.css);.a{fill:url(#b)}` +
+		`.c{background:URL( 'https://evil.example.com/track.png' )}</style><rect class="a"/></svg>`
+
+	sanitized, err := sanitizeSvg([]byte(svg))
+
+	assert.NoError(t, err)
+	assert.Equal(t, `<svg><style>.a{fill:url(#b)}.c{background:none}</style><rect class="a"></rect></svg>`, string(sanitized))
+}
+
+func TestSanitizeSvgRemovesStylesWithEscapes(t *testing.T) {
+	svg := `<svg><style>.a{background:u\72l(https://evil.example.com/track.png)}</style></svg>`
+
+	sanitized, err := sanitizeSvg([]byte(svg))
+
+	assert.NoError(t, err)
+	assert.Equal(t, `<svg><style></style></svg>`, string(sanitized))
+}
+
+func TestSanitizeSvgSanitizesStylesSplitByCommentsAndElements(t *testing.T) {
+	svg := `<svg><style>@imp<!-- -->ort "https://evil.example.com/a.css";.a{fill:u<g/>rl(https://evil.example.com/b.png)}</style></svg>`
+
+	sanitized, err := sanitizeSvg([]byte(svg))
+
+	assert.NoError(t, err)
+	assert.Equal(t, `<svg><style>.a{fill:none}</style></svg>`, string(sanitized))
+}
+
+func TestSanitizeSvgKeepsStylesOfPluginSetImages(t *testing.T) {
+	for file, rule := range map[string]string{
+		"resources/test/plugin-sets/proper-plugin-sets/plug-and-play/standard.svg": ".d{fill:url(#b)}",
+		"resources/test/plugin-sets/proper-plugin-sets/plug-and-play/check.svg":    ".e{fill:url(#b)}.f{fill:#00c79b}",
+		"resources/test/plugin-sets/proper-plugin-sets/administration/check.svg":   ".dz{fill:none;stroke:#00c79b;stroke-miterlimit:10}",
+	} {
+		data, err := ioutil.ReadFile(file)
+		assert.NoError(t, err)
+
+		sanitized, err := sanitizeSvg(data)
+
+		assert.NoError(t, err)
+		assert.Contains(t, string(sanitized), "<style>"+rule+"</style>", file)
+	}
+}
+
+func TestSanitizeSvgRemovesExternalUrlsFromAttributes(t *testing.T) {
+	svg := `<svg><rect style="fill:red;background:url(https://evil.example.com/track.png)"/>` +
+		`<rect style="background:URL( 'https://evil.example.com/track.png' )"/>` +
+		`<rect style="background:u\72l(https://evil.example.com/track.png)"/>` +
+		`<rect fill="url(https://evil.example.com/track.svg#p)"/>` +
+		`<rect style="fill:url(#gradient)" fill="url('#pattern')"/>` +
+		`<rect style="fill:url(data:image/png;base64,AAAA)"/></svg>`
+
+	sanitized, err := sanitizeSvg([]byte(svg))
+
+	assert.NoError(t, err)
+	assert.Equal(t, `<svg><rect></rect><rect></rect><rect></rect><rect></rect>`+
+		`<rect style="fill:url(#gradient)" fill="url(&#39;#pattern&#39;)"></rect>`+
+		`<rect style="fill:url(data:image/png;base64,AAAA)"></rect></svg>`, string(sanitized))
+}
+
+func TestSanitizeSvgFailsForOtherDocuments(t *testing.T) {
+	_, err := sanitizeSvg([]byte(`<html><body></body></html>`))
+	assert.Error(t, err)
+
+	_, err = sanitizeSvg([]byte(`<svg><g></svg>`))
+	assert.Error(t, err)
+
+	_, err = sanitizeSvg([]byte(``))
+	assert.Error(t, err)
+}
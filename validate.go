@@ -0,0 +1,165 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+)
+
+func runValidate(args []string, out io.Writer) int {
+	flags := flag.NewFlagSet("validate", flag.ContinueOnError)
+	flags.SetOutput(out)
+	pluginDirectory := flags.String("plugins", "", "directory with plugin descriptors (default descriptor-directory of the configuration)")
+	pluginSetsDirectory := flags.String("plugin-sets", "", "directory with plugin sets (default plugin-sets-directory of the configuration)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *pluginDirectory == "" || *pluginSetsDirectory == "" {
+		configuration := readConfiguration()
+		if *pluginDirectory == "" {
+			*pluginDirectory = configuration.DescriptorDirectory
+		}
+		if *pluginSetsDirectory == "" {
+			*pluginSetsDirectory = configuration.PluginSetsDirectory
+		}
+	}
+
+	problems := validateCatalog(*pluginDirectory, *pluginSetsDirectory)
+	for _, problem := range problems {
+		_, _ = fmt.Fprintln(out, problem)
+	}
+	if len(problems) > 0 {
+		_, _ = fmt.Fprintf(out, "found %d problems\n", len(problems))
+		return 1
+	}
+	_, _ = fmt.Fprintln(out, "no problems found")
+	return 0
+}
+
+func validateCatalog(pluginDirectory string, pluginSetsDirectory string) Problems {
+	var problems Problems
+	plugins := validatePlugins(&problems, pluginDirectory)
+	validatePluginSets(&problems, pluginSetsDirectory, plugins)
+	return problems
+}
+
+func validatePlugins(problems *Problems, directory string) map[string]bool {
+	plugins := make(map[string]bool)
+
+	scannedPlugins, scanProblems, err := scanDirectory(directory)
+	if err != nil {
+		problems.Add(directory, 0, "", err.Error())
+		return plugins
+	}
+	*problems = append(*problems, scanProblems...)
+
+	for _, plugin := range scannedPlugins {
+		plugins[plugin.Name] = true
+	}
+
+	// the scanner ignores unknown keys, so we have to check them separately
+	pluginYmls, _ := filepath.Glob(filepath.Join(directory, "*", "plugin.yml"))
+	for _, pluginYml := range pluginYmls {
+		checkUnknownKeys(problems, pluginYml, &Plugin{})
+	}
+	translationYmls, _ := filepath.Glob(filepath.Join(directory, "*", "plugin_*.yml"))
+	for _, translationYml := range translationYmls {
+		checkUnknownKeys(problems, translationYml, &PluginTranslation{})
+	}
+	for _, pattern := range []string{"*.yml", "*.yaml"} {
+		releaseYmls, _ := filepath.Glob(filepath.Join(directory, "*", "releases", pattern))
+		for _, releaseYml := range releaseYmls {
+			checkUnknownKeys(problems, releaseYml, &Release{})
+		}
+	}
+
+	return plugins
+}
+
+func validatePluginSets(problems *Problems, directory string, plugins map[string]bool) {
+	pluginSetDirectories, err := ioutil.ReadDir(directory)
+	if err != nil {
+		problems.Add(directory, 0, "", "could not open plugin sets directory: "+err.Error())
+		return
+	}
+
+	for _, pluginSetDirectory := range pluginSetDirectories {
+		if !pluginSetDirectory.IsDir() {
+			continue
+		}
+		path := filepath.Join(directory, pluginSetDirectory.Name())
+
+		pluginsYml := filepath.Join(path, "plugins.yml")
+		var pluginsYmlContent Plugins
+		data, strict := readStrict(problems, pluginsYml, &pluginsYmlContent)
+
+		descriptionYmls, _ := filepath.Glob(filepath.Join(path, "description_*.yml"))
+		for _, descriptionYml := range descriptionYmls {
+			var description Description
+			_, ok := readStrict(problems, descriptionYml, &description)
+			strict = strict && ok
+		}
+
+		pluginSet, err := readPluginSetDirectory(path)
+		if err != nil {
+			// problems which were already reported by the strict read are not reported twice
+			if strict {
+				problems.Add(path, 0, "", err.Error())
+			}
+			continue
+		}
+
+		for _, name := range pluginSet.Plugins {
+			if !plugins[name] {
+				problems.Add(pluginsYml, findListItemLine(data, name), "plugins", fmt.Sprintf("plugin %s does not exist", name))
+			}
+		}
+	}
+}
+
+// checkUnknownKeys reports keys of the file which are unknown for the value.
+// Other errors are not reported, because they are already reported by the scanner.
+func checkUnknownKeys(problems *Problems, file string, value interface{}) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return
+	}
+	err = yaml.UnmarshalStrict(data, value)
+	typeError, ok := err.(*yaml.TypeError)
+	if !ok {
+		return
+	}
+
+	knownErrors := make(map[string]bool)
+	if lenientTypeError, ok := yaml.Unmarshal(data, value).(*yaml.TypeError); ok {
+		for _, message := range lenientTypeError.Errors {
+			knownErrors[message] = true
+		}
+	}
+	for _, message := range typeError.Errors {
+		if !knownErrors[message] {
+			problems.AddYamlError(file, &yaml.TypeError{Errors: []string{message}})
+		}
+	}
+}
+
+// readStrict unmarshals the file into the value and reports syntax errors and unknown keys.
+// The returned flag is false, if the file could not be read or parsed at all.
+func readStrict(problems *Problems, file string, value interface{}) ([]byte, bool) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		problems.Add(file, 0, "", "could not read file: "+err.Error())
+		return nil, false
+	}
+	err = yaml.UnmarshalStrict(data, value)
+	if err != nil {
+		problems.AddYamlError(file, err)
+		_, isTypeError := err.(*yaml.TypeError)
+		return data, isTypeError
+	}
+	return data, true
+}
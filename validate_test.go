@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const (
+	brokenPlugins    = "resources/test/validate/broken/plugins"
+	brokenPluginSets = "resources/test/validate/broken/plugin-sets"
+	brokenReleases   = "resources/test/validate/broken/plugins/scm-broken-plugin/releases/"
+)
+
+func TestValidateCatalogReportsAllProblems(t *testing.T) {
+	problems := validateCatalog(brokenPlugins, brokenPluginSets)
+
+	assert.Len(t, problems, 11)
+}
+
+func TestValidateCatalogReportsInvalidTag(t *testing.T) {
+	problems := validateCatalog(brokenPlugins, brokenPluginSets)
+
+	assert.Contains(t, problems, Problem{File: brokenReleases + "next.yml", Line: 2, Field: "tag", Message: "next is not a valid version"})
+}
+
+func TestValidateCatalogReportsInvalidMinVersion(t *testing.T) {
+	problems := validateCatalog(brokenPlugins, brokenPluginSets)
+
+	assert.Contains(t, problems, Problem{File: brokenReleases + "next.yml", Line: 5, Field: "conditions.minVersion", Message: "two is not a valid version"})
+}
+
+func TestValidateCatalogReportsMissingUrlAndChecksum(t *testing.T) {
+	problems := validateCatalog(brokenPlugins, brokenPluginSets)
+
+	assert.Contains(t, problems, Problem{File: brokenReleases + "next.yml", Field: "url", Message: "url is missing"})
+	assert.Contains(t, problems, Problem{File: brokenReleases + "next.yml", Field: "checksum", Message: "checksum is missing"})
+}
+
+func TestValidateCatalogReportsUnknownKeys(t *testing.T) {
+	problems := validateCatalog(brokenPlugins, brokenPluginSets)
+
+	assert.Contains(t, problems, Problem{File: brokenReleases + "next.yml", Line: 6, Message: "field unknown not found in type main.Conditions"})
+	assert.Contains(t, problems, Problem{File: brokenPluginSets + "/broken-set/plugins.yml", Line: 4, Message: "field images not found in type main.Plugins"})
+	assert.Contains(t, problems, Problem{File: brokenPlugins + "/scm-broken-plugin/plugin_de.yml", Line: 2, Message: "field label not found in type main.PluginTranslation"})
+}
+
+func TestValidateCatalogReportsSyntaxErrors(t *testing.T) {
+	problems := validateCatalog(brokenPlugins, brokenPluginSets)
+
+	assert.Contains(t, problems, Problem{File: brokenReleases + "syntax.yml", Line: 2, Message: "did not find expected ',' or ']'"})
+}
+
+func TestValidateCatalogReportsDuplicateTags(t *testing.T) {
+	problems := validateCatalog(brokenPlugins, brokenPluginSets)
+
+	assert.Contains(t, problems, Problem{
+		File:    brokenReleases + "1-0-0.yml",
+		Line:    2,
+		Field:   "tag",
+		Message: "duplicate tag 1.0.0, already defined in " + brokenReleases + "1-0-0-again.yml",
+	})
+}
+
+func TestValidateCatalogReportsMissingPluginName(t *testing.T) {
+	problems := validateCatalog(brokenPlugins, brokenPluginSets)
+
+	assert.Contains(t, problems, Problem{File: brokenPlugins + "/scm-unparseable-plugin/plugin.yml", Field: "name", Message: "name is missing"})
+}
+
+func TestValidateCatalogReportsUnknownPluginsInPluginSets(t *testing.T) {
+	problems := validateCatalog(brokenPlugins, brokenPluginSets)
+
+	assert.Contains(t, problems, Problem{File: brokenPluginSets + "/broken-set/plugins.yml", Line: 7, Field: "plugins", Message: "plugin scm-missing-plugin does not exist"})
+}
+
+func TestValidateCatalogReportsBrokenPluginSets(t *testing.T) {
+	problems := validateCatalog("resources/test/validate/clean/plugins", "resources/test/plugin-sets/plugin-sets-no-features")
+
+	assert.Len(t, problems, 1)
+	assert.Contains(t, problems[0].Message, "features are missing at")
+}
+
+func TestRunValidatePrintsProblems(t *testing.T) {
+	var out bytes.Buffer
+
+	code := runValidate([]string{"-plugins", brokenPlugins, "-plugin-sets", brokenPluginSets}, &out)
+
+	assert.Equal(t, 1, code)
+	assert.Contains(t, out.String(), brokenReleases+"next.yml:2: tag: next is not a valid version")
+	assert.Contains(t, out.String(), "found 11 problems")
+}
+
+func TestRunValidateWithoutProblems(t *testing.T) {
+	var out bytes.Buffer
+
+	code := runValidate([]string{"-plugins", "resources/test/validate/clean/plugins", "-plugin-sets", "resources/test/validate/clean/plugin-sets"}, &out)
+
+	assert.Equal(t, 0, code)
+	assert.Contains(t, out.String(), "no problems found")
+}
+
+func TestProblemString(t *testing.T) {
+	assert.Equal(t, "a.yml:3: tag: broken", Problem{File: "a.yml", Line: 3, Field: "tag", Message: "broken"}.String())
+	assert.Equal(t, "a.yml: broken", Problem{File: "a.yml", Message: "broken"}.String())
+}
@@ -3,6 +3,8 @@ package main
 import (
 	"github.com/hashicorp/go-version"
 	"log"
+	"strconv"
+	"strings"
 )
 
 func less(releases []Release) func(int, int) bool {
@@ -22,3 +24,31 @@ func isLess(versionString1 string, versionString2 string) bool {
 	}
 	return v1.LessThan(v2)
 }
+
+// versionAtMost checks whether the given version is not higher than the max value. Only the segments
+// specified by the max value are compared, so that a max value of 2.0 includes 2.0.12.
+func versionAtMost(v *version.Version, maxValue string) (bool, error) {
+	maxVersion, err := version.NewVersion(maxValue)
+	if err != nil {
+		return false, err
+	}
+	precision := strings.Count(strings.FieldsFunc(maxValue, isVersionSuffixSeparator)[0], ".") + 1
+
+	segments := v.Segments()
+	if precision < len(segments) {
+		segments = segments[:precision]
+	}
+	values := make([]string, len(segments))
+	for i, segment := range segments {
+		values[i] = strconv.Itoa(segment)
+	}
+	truncated, err := version.NewVersion(strings.Join(values, "."))
+	if err != nil {
+		return false, err
+	}
+	return truncated.LessThanOrEqual(maxVersion), nil
+}
+
+func isVersionSuffixSeparator(r rune) bool {
+	return r == '-' || r == '+'
+}
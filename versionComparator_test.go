@@ -1,6 +1,7 @@
 package main
 
 import (
+	"github.com/hashicorp/go-version"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
@@ -28,3 +29,13 @@ func TestVersionComparisonWithDifferentVersionParts(t *testing.T) {
 func TestVersionComparisonWithLettersDoesNotFail(t *testing.T) {
 	isLess("1.a", "1.1")
 }
+
+func TestVersionAtMost(t *testing.T) {
+	v := version.Must(version.NewVersion("2.0.1"))
+
+	for maxValue, expected := range map[string]bool{"2": true, "2.0": true, "2.0.0": false, "2.0.1": true, "1.9": false} {
+		ok, err := versionAtMost(v, maxValue)
+		assert.NoError(t, err, maxValue)
+		assert.Equal(t, expected, ok, maxValue)
+	}
+}
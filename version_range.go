@@ -24,6 +24,15 @@ func (r *VersionRange) MarshalJSON() ([]byte, error) {
 	return json.Marshal(r.Value)
 }
 
+func (r VersionRange) MarshalYAML() (interface{}, error) {
+	return r.Value, nil
+}
+
+// IsZero is used to omit empty ranges in yaml
+func (r VersionRange) IsZero() bool {
+	return r.Value == ""
+}
+
 func (r *VersionRange) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	var buf string
 	err := unmarshal(&buf)